@@ -18,7 +18,8 @@ import (
 
 	"github.com/chzyer/readline"
 	"github.com/fatih/color"
-	"github.com/pinpt/go-common/fileutil"
+	"github.com/pinpt/go-common/v10/fileutil"
+	"github.com/pinpt/go-dremio/dremio"
 
 	// load the db driver
 	_ "github.com/pinpt/go-dremio/driver"
@@ -258,22 +259,12 @@ func Execute(ctx context.Context, conn *sql.DB, query string) ([]map[string]inte
 	if err != nil {
 		return nil, nil, err
 	}
-	count := len(columns)
-	values := make([]interface{}, count)
-	scanArgs := make([]interface{}, count)
-	for i := range values {
-		scanArgs[i] = &values[i]
-	}
 	var masterData []map[string]interface{}
 	for rows.Next() {
-		err := rows.Scan(scanArgs...)
+		m, err := dremio.ScanMap(rows)
 		if err != nil {
 			return nil, nil, err
 		}
-		m := make(map[string]interface{})
-		for i, v := range values {
-			m[columns[i]] = v
-		}
 		masterData = append(masterData, m)
 	}
 	return masterData, columns, nil
@@ -10,7 +10,9 @@ import (
 	"strings"
 
 	"github.com/fatih/color"
-	pstrings "github.com/pinpt/go-common/strings"
+	pstrings "github.com/pinpt/go-common/v10/strings"
+	"github.com/pinpt/go-dremio/dremio"
+	"github.com/pinpt/go-dremio/dremiosql"
 )
 
 var showTables = Plugin{
@@ -64,52 +66,23 @@ func showHelpFunc(ctx context.Context, conn *sql.DB, input string) error {
 }
 
 func showTablesFunc(ctx context.Context, conn *sql.DB, query string) error {
-	var tablename string
+	filter := dremio.TableFilter{Types: []string{dremio.TableTypeTable, dremio.TableTypeView}}
 	parts := strings.Split(query, " ")
 	if len(parts) > 2 {
-		tablename = parts[2]
+		filter.Contains = parts[2]
 	}
-	q := `SELECT CONCAT(TABLE_SCHEMA, '.', TABLE_NAME) FROM INFORMATION_SCHEMA."TABLES" WHERE TABLE_TYPE IN ('TABLE', 'VIEW')`
-	if tablename != "" {
-		q += ` AND CONCAT(TABLE_SCHEMA, '.', TABLE_NAME) LIKE '%%` + tablename + `%%'`
-	}
-	rows, err := conn.QueryContext(ctx, q)
+	tables, err := dremio.ListTables(ctx, conn, filter)
 	if err != nil {
 		return err
 	}
-	for rows.Next() {
-		var name string
-		rows.Scan(&name)
-		fmt.Println(color.HiWhiteString("  " + JoinWords(strings.Split(name, "."), ".")))
+	for _, table := range tables {
+		fmt.Println(color.HiWhiteString("  " + table.String()))
 	}
 	return nil
 }
 
-func dequote(val string) string {
-	if val[0:1] == `"` {
-		return val[1 : len(val)-1]
-	}
-	return val
-}
-
 func describeTablesFunc(ctx context.Context, conn *sql.DB, query string) error {
-	table := strings.TrimSpace(query[5:])
-	tok := strings.Split(table, ".")
-	sql := `SELECT TABLE_SCHEMA, COLUMN_NAME, DATA_TYPE FROM INFORMATION_SCHEMA."COLUMNS" `
-	var hasschema bool
-	if len(tok) > 1 {
-		schemastrs := make([]string, 0)
-		for _, s := range tok[0 : len(tok)-1] {
-			schemastrs = append(schemastrs, dequote(s))
-		}
-		schema := strings.Join(schemastrs, ".")
-		table = dequote(tok[len(tok)-1])
-		hasschema = true
-		sql += `WHERE TABLE_SCHEMA = '` + schema + `' AND TABLE_NAME = '` + table + "' order by ORDINAL_POSITION"
-	} else {
-		sql += `WHERE TABLE_NAME = '` + table + "' order by TABLE_SCHEMA, ORDINAL_POSITION"
-	}
-	rows, err := conn.QueryContext(ctx, sql)
+	path, err := dremiosql.SplitPath(strings.TrimSpace(query[5:]))
 	if err != nil {
 		return err
 	}
@@ -119,19 +92,30 @@ func describeTablesFunc(ctx context.Context, conn *sql.DB, query string) error {
 	}
 	var all []res
 	padding := float64(20)
-	for rows.Next() {
-		var schema string
-		var name string
-		var each res
-		rows.Scan(&schema, &name, &each.tableType)
-		if hasschema {
-			each.tableName = name
-		} else {
-			each.tableName = JoinWords([]string{schema, name}, ".")
-		}
+	add := func(each res) {
 		padding = math.Max(float64(len(each.tableName)), padding)
 		all = append(all, each)
 	}
+	if len(path) > 1 {
+		info, err := dremio.DescribeTable(ctx, conn, path...)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if info != nil {
+			for _, column := range info.Columns {
+				add(res{column.Name, column.Type})
+			}
+		}
+	} else {
+		// the tables with the name in every schema, in a single query
+		columns, err := dremio.ListColumns(ctx, conn, path[0])
+		if err != nil {
+			return err
+		}
+		for _, column := range columns {
+			add(res{dremiosql.QuotePath(append(strings.Split(column.Schema, "."), column.Name)...), column.Type})
+		}
+	}
 	for _, r := range all {
 		fmt.Println(color.HiWhiteString(" " + pstrings.PadRight(r.tableName, int(padding), ' ') + "   " + color.CyanString(r.tableType)))
 	}
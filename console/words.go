@@ -0,0 +1,25 @@
+package console
+
+import (
+	"strings"
+
+	"github.com/pinpt/go-dremio/dremiosql"
+)
+
+// Word returns a dremio-friendly word
+//
+// Deprecated: use dremiosql.QuoteIdentifier
+func Word(str string) string {
+	return dremiosql.QuoteIdentifier(str)
+}
+
+// JoinWords similar to strings.Join but surrounding reserved words in double quotes
+//
+// Deprecated: use dremiosql.QuotePath for paths
+func JoinWords(words []string, deli string) string {
+	str := make([]string, len(words))
+	for i, w := range words {
+		str[i] = Word(w)
+	}
+	return strings.Join(str, deli)
+}
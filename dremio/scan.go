@@ -0,0 +1,394 @@
+// Package dremio provides helpers on top of database/sql for the values returned by the dremio driver.
+//
+//	type Order struct {
+//		ID    int64   `dremio:"order_id"`
+//		Total float64 // matches a total column, regardless of its case
+//	}
+//	orders, err := dremio.Select[Order](ctx, db, "SELECT order_id, total FROM orders")
+package dremio
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Queryer runs queries, such as a *sql.DB, *sql.Conn or *sql.Tx
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Select runs the query and returns its rows as values of T. T can be a struct, whose
+// fields are matched to the columns by their dremio tag or else by their name regardless
+// of its case, a map[string]interface{} of the columns, or any other type for a query
+// with a single column
+func Select[T any](ctx context.Context, db Queryer, query string, args ...interface{}) ([]T, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	s, err := newScanner[T](rows)
+	if err != nil {
+		return nil, err
+	}
+	var res []T
+	for rows.Next() {
+		var val T
+		if err := s.scan(rows, &val); err != nil {
+			return nil, err
+		}
+		res = append(res, val)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Get runs the query and returns its first row as a T, like Select. It returns
+// sql.ErrNoRows if the query returned no rows
+func Get[T any](ctx context.Context, db Queryer, query string, args ...interface{}) (T, error) {
+	var val T
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return val, err
+	}
+	defer rows.Close()
+	s, err := newScanner[T](rows)
+	if err != nil {
+		return val, err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return val, err
+		}
+		return val, sql.ErrNoRows
+	}
+	if err := s.scan(rows, &val); err != nil {
+		return val, err
+	}
+	return val, rows.Close()
+}
+
+// ScanMap scans the current row into a map of the values by column name
+func ScanMap(rows *sql.Rows) (map[string]interface{}, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(columns))
+	scanArgs := make([]interface{}, len(columns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+	if err := rows.Scan(scanArgs...); err != nil {
+		return nil, err
+	}
+	m := make(map[string]interface{}, len(columns))
+	for i, v := range values {
+		m[columns[i]] = v
+	}
+	return m, nil
+}
+
+// scanner scans rows into values of a type
+type scanner struct {
+	columns []string
+	// fields are the index of the struct field of each column, nil for a column without one
+	fields [][]int
+	kind   int
+}
+
+const (
+	scanStruct = iota
+	scanMap
+	scanValue
+)
+
+var mapType = reflect.TypeOf(map[string]interface{}{})
+
+func newScanner[T any](rows *sql.Rows) (*scanner, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	s := &scanner{columns: columns}
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	switch {
+	case t == mapType:
+		s.kind = scanMap
+	case t.Kind() == reflect.Struct && t != timeType:
+		s.kind = scanStruct
+		fields := structFields(t)
+		s.fields = make([][]int, len(columns))
+		for i, col := range columns {
+			if index, ok := fields[col]; ok {
+				s.fields[i] = index
+			} else {
+				s.fields[i] = fields[strings.ToLower(col)]
+			}
+		}
+	default:
+		if len(columns) != 1 {
+			return nil, fmt.Errorf("expected a single column to scan into %v but the query returned %d", t, len(columns))
+		}
+		s.kind = scanValue
+	}
+	return s, nil
+}
+
+func (s *scanner) scan(rows *sql.Rows, dest interface{}) error {
+	switch s.kind {
+	case scanMap:
+		m, err := ScanMap(rows)
+		if err != nil {
+			return err
+		}
+		reflect.ValueOf(dest).Elem().Set(reflect.ValueOf(m))
+		return nil
+	case scanValue:
+		var v interface{}
+		if err := rows.Scan(&v); err != nil {
+			return err
+		}
+		return assign(reflect.ValueOf(dest).Elem(), v)
+	}
+	values := make([]interface{}, len(s.columns))
+	scanArgs := make([]interface{}, len(s.columns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+	if err := rows.Scan(scanArgs...); err != nil {
+		return err
+	}
+	val := reflect.ValueOf(dest).Elem()
+	for i, index := range s.fields {
+		if index == nil {
+			continue
+		}
+		if err := assign(fieldByIndex(val, index), values[i]); err != nil {
+			return fmt.Errorf("error scanning column %s. %v", s.columns[i], err)
+		}
+	}
+	return nil
+}
+
+var fieldCache sync.Map
+
+// structFields returns the index of the fields of the struct by their column name, the name of
+// the dremio tag or the field name. fields are also keyed by the lower case of that name
+func structFields(t reflect.Type) map[string][]int {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.(map[string][]int)
+	}
+	fields := make(map[string][]int)
+	var walk func(t reflect.Type, parent []int)
+	walk = func(t reflect.Type, parent []int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			index := append(append([]int(nil), parent...), i)
+			tag := f.Tag.Get("dremio")
+			if tag == "-" {
+				continue
+			}
+			if f.Anonymous && tag == "" {
+				ft := f.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					walk(ft, index)
+					continue
+				}
+			}
+			if f.PkgPath != "" {
+				// unexported
+				continue
+			}
+			name := tag
+			if tag != "" {
+				fields[tag] = index
+			} else {
+				name = f.Name
+				if _, ok := fields[name]; !ok {
+					fields[name] = index
+				}
+			}
+			if _, ok := fields[strings.ToLower(name)]; !ok {
+				fields[strings.ToLower(name)] = index
+			}
+		}
+	}
+	walk(t, nil)
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// fieldByIndex returns the field, allocating the embedded struct pointers on the way
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.000",
+	"2006-01-02 15:04:05",
+	time.RFC3339Nano,
+	"2006-01-02",
+	"15:04:05.000",
+	"15:04:05",
+}
+
+// assign sets the destination to a value returned by the driver, converting it when needed
+// such as for the numbers and dates of the REST API, which are float64 and string
+func assign(dest reflect.Value, v interface{}) error {
+	if dest.CanAddr() && dest.Addr().Type().Implements(scannerType) {
+		return dest.Addr().Interface().(sql.Scanner).Scan(v)
+	}
+	if v == nil {
+		dest.Set(reflect.Zero(dest.Type()))
+		return nil
+	}
+	if dest.Kind() == reflect.Ptr {
+		ptr := reflect.New(dest.Type().Elem())
+		if err := assign(ptr.Elem(), v); err != nil {
+			return err
+		}
+		dest.Set(ptr)
+		return nil
+	}
+	src := reflect.ValueOf(v)
+	if src.Type().AssignableTo(dest.Type()) {
+		dest.Set(src)
+		return nil
+	}
+	switch dest.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		switch src.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return assignNumber(dest, src)
+		}
+	case reflect.String:
+		switch val := v.(type) {
+		case []byte:
+			dest.SetString(string(val))
+		default:
+			dest.SetString(fmt.Sprint(v))
+		}
+		return nil
+	case reflect.Struct:
+		if s, ok := v.(string); ok && dest.Type() == timeType {
+			for _, layout := range timeLayouts {
+				if t, err := time.Parse(layout, s); err == nil {
+					dest.Set(reflect.ValueOf(t))
+					return nil
+				}
+			}
+			return fmt.Errorf("cannot parse %q as a time", s)
+		}
+	}
+	if dest.Kind() == reflect.Interface && src.Type().Implements(dest.Type()) {
+		dest.Set(src)
+		return nil
+	}
+	return fmt.Errorf("cannot assign %T to %v", v, dest.Type())
+}
+
+// assignNumber sets the destination to a number of another type, returning an error instead of
+// overflowing the destination or dropping the fraction of the number
+func assignNumber(dest reflect.Value, src reflect.Value) error {
+	overflow := fmt.Errorf("%v overflows %v", src, dest.Type())
+	precision := fmt.Errorf("%v can't be represented exactly as %v", src, dest.Type())
+	// the number as an integer, when it's one
+	var n int64
+	var u uint64
+	var f float64
+	var negative, integer bool
+	switch src.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = src.Int()
+		u, f, negative, integer = uint64(n), float64(n), n < 0, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u = src.Uint()
+		n, f, integer = int64(u), float64(u), true
+		if u > math.MaxInt64 {
+			n = -1
+		}
+	default:
+		f = src.Float()
+		negative = f < 0
+	}
+	switch dest.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !integer {
+			if f != math.Trunc(f) {
+				return precision
+			}
+			if f < math.MinInt64 || f >= math.MaxInt64 {
+				return overflow
+			}
+			n = int64(f)
+		} else if n < 0 && !negative {
+			// a uint64 larger than any int64
+			return overflow
+		}
+		if dest.OverflowInt(n) {
+			return overflow
+		}
+		dest.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if negative {
+			return overflow
+		}
+		if !integer {
+			if f != math.Trunc(f) {
+				return precision
+			}
+			if f >= math.MaxUint64 {
+				return overflow
+			}
+			u = uint64(f)
+		}
+		if dest.OverflowUint(u) {
+			return overflow
+		}
+		dest.SetUint(u)
+	default:
+		if dest.OverflowFloat(f) {
+			return overflow
+		}
+		if integer {
+			// large integers have more digits than a float holds
+			g := f
+			if dest.Kind() == reflect.Float32 {
+				g = float64(float32(f))
+			}
+			if g >= math.MaxUint64 || (negative && int64(g) != n) || (!negative && uint64(g) != u) {
+				return precision
+			}
+		}
+		dest.SetFloat(f)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package dremio_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/pinpt/go-dremio/dremio"
+	"github.com/pinpt/go-dremio/dremiotest"
+	"github.com/pinpt/go-dremio/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+type audit struct {
+	Created time.Time `dremio:"created_at"`
+}
+
+type order struct {
+	audit
+	ID       int64 `dremio:"order_id"`
+	Customer string
+	Total    *float64
+	Paid     sql.NullBool
+	Ignored  string `dremio:"-"`
+}
+
+func newTestDB(t *testing.T) *sql.DB {
+	srv := dremiotest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.Handle(`^SELECT order_id FROM orders`, dremiotest.Result{
+		Columns: []dremiotest.Column{{Name: "order_id", Type: "BIGINT"}},
+		Rows:    [][]interface{}{{1}, {2}},
+	})
+	srv.Handle(`FROM orders`, dremiotest.Result{
+		Columns: []dremiotest.Column{
+			{Name: "order_id", Type: "BIGINT"},
+			{Name: "CUSTOMER"},
+			{Name: "total", Type: "DOUBLE"},
+			{Name: "paid", Type: "BOOLEAN"},
+			{Name: "created_at", Type: "TIMESTAMP"},
+			{Name: "ignored"},
+		},
+		Rows: [][]interface{}{
+			{1, "acme", 9.5, true, "2019-01-02 03:04:05.000", "x"},
+			{2, "globex", nil, nil, "2019-01-03 03:04:05.000", "y"},
+		},
+	})
+	srv.Handle(`FROM empty`, dremiotest.Result{Columns: []dremiotest.Column{{Name: "a"}}})
+	srv.Handle(`FROM numbers`, dremiotest.Result{
+		Columns: []dremiotest.Column{{Name: "BIG", Type: "BIGINT"}, {Name: "ratio", Type: "DOUBLE"}},
+		Rows:    [][]interface{}{{5000000000, 1.5}},
+	})
+	db, err := sql.Open(driver.DriverName, srv.DSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSelect(t *testing.T) {
+	assert := assert.New(t)
+	db := newTestDB(t)
+	ctx := context.Background()
+	orders, err := dremio.Select[order](ctx, db, "SELECT * FROM orders")
+	assert.NoError(err)
+	assert.Len(orders, 2)
+	assert.Equal(int64(1), orders[0].ID)
+	assert.Equal("acme", orders[0].Customer)
+	assert.Equal(9.5, *orders[0].Total)
+	assert.Equal(sql.NullBool{Bool: true, Valid: true}, orders[0].Paid)
+	assert.Equal(time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC), orders[0].Created)
+	assert.Empty(orders[0].Ignored)
+	assert.Nil(orders[1].Total)
+	assert.False(orders[1].Paid.Valid)
+
+	ids, err := dremio.Select[int](ctx, db, "SELECT order_id FROM orders")
+	assert.NoError(err)
+	assert.Equal([]int{1, 2}, ids)
+
+	maps, err := dremio.Select[map[string]interface{}](ctx, db, "SELECT * FROM orders")
+	assert.NoError(err)
+	assert.Equal("globex", maps[1]["CUSTOMER"])
+}
+
+func TestGet(t *testing.T) {
+	assert := assert.New(t)
+	db := newTestDB(t)
+	ctx := context.Background()
+	o, err := dremio.Get[order](ctx, db, "SELECT * FROM orders")
+	assert.NoError(err)
+	assert.Equal(int64(1), o.ID)
+	_, err = dremio.Get[order](ctx, db, "SELECT * FROM empty")
+	assert.Equal(sql.ErrNoRows, err)
+	_, err = dremio.Get[int](ctx, db, "SELECT * FROM orders")
+	assert.Error(err)
+}
+
+func TestScanNumbers(t *testing.T) {
+	assert := assert.New(t)
+	db := newTestDB(t)
+	ctx := context.Background()
+	type wide struct {
+		Big   int64   `dremio:"big"`
+		Ratio float64 `dremio:"ratio"`
+	}
+	w, err := dremio.Get[wide](ctx, db, "SELECT * FROM numbers")
+	assert.NoError(err)
+	// the tag matches the column regardless of its case
+	assert.Equal(wide{Big: 5000000000, Ratio: 1.5}, w)
+
+	type narrow struct {
+		Big int32 `dremio:"big"`
+	}
+	_, err = dremio.Get[narrow](ctx, db, "SELECT * FROM numbers")
+	assert.EqualError(err, "error scanning column BIG. 5e+09 overflows int32")
+	type truncated struct {
+		Ratio int64
+	}
+	_, err = dremio.Get[truncated](ctx, db, "SELECT * FROM numbers")
+	assert.EqualError(err, "error scanning column ratio. 1.5 can't be represented exactly as int64")
+}
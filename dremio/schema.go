@@ -0,0 +1,247 @@
+package dremio
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/pinpt/go-dremio/dremiosql"
+)
+
+// The types of tables
+const (
+	TableTypeTable       = "TABLE"
+	TableTypeView        = "VIEW"
+	TableTypeSystemTable = "SYSTEM_TABLE"
+)
+
+// Schema is a schema of the catalog, such as a source, a space or one of their folders
+type Schema struct {
+	Catalog string `dremio:"CATALOG_NAME"`
+	// Name is the path of the schema joined by dots, such as sales.2019
+	Name    string `dremio:"SCHEMA_NAME"`
+	Owner   string `dremio:"SCHEMA_OWNER"`
+	Type    string `dremio:"TYPE"`
+	Mutable bool   `dremio:"-"`
+}
+
+// Table is a table or view of a schema
+type Table struct {
+	Catalog string `dremio:"TABLE_CATALOG"`
+	Schema  string `dremio:"TABLE_SCHEMA"`
+	Name    string `dremio:"TABLE_NAME"`
+	// Type is one of the TableType constants
+	Type string `dremio:"TABLE_TYPE"`
+}
+
+// Path returns the path of the table, the parts of its schema followed by its name. The
+// INFORMATION_SCHEMA joins the parts of the schema with dots, so a source, space or folder
+// whose name contains a dot isn't supported
+func (t Table) Path() []string {
+	var path []string
+	if t.Schema != "" {
+		path = strings.Split(t.Schema, ".")
+	}
+	return append(path, t.Name)
+}
+
+// String returns the path of the table, quoted to be used in a query
+func (t Table) String() string {
+	return dremiosql.QuotePath(t.Path()...)
+}
+
+// Column is a column of a table
+type Column struct {
+	Name string `dremio:"COLUMN_NAME"`
+	// Ordinal is the position of the column in the table, starting at 1
+	Ordinal  int    `dremio:"ORDINAL_POSITION"`
+	Type     string `dremio:"DATA_TYPE"`
+	Nullable bool   `dremio:"-"`
+	// MaxLength is the maximum length of character and binary columns
+	MaxLength *int `dremio:"CHARACTER_MAXIMUM_LENGTH"`
+	// Precision is the precision of numeric columns
+	Precision *int `dremio:"NUMERIC_PRECISION"`
+	// Scale is the scale of numeric columns
+	Scale *int `dremio:"NUMERIC_SCALE"`
+	// DateTimePrecision is the fractional seconds precision of time and timestamp columns
+	DateTimePrecision *int `dremio:"DATETIME_PRECISION"`
+}
+
+// TableInfo is the description of a table
+type TableInfo struct {
+	Table
+	Columns []Column
+	// ViewDefinition is the SQL of a view
+	ViewDefinition string
+}
+
+// TableFilter restricts the tables returned by ListTables. the zero value matches all the tables
+type TableFilter struct {
+	// Schema is the exact name of the schema of the tables, such as sales.2019
+	Schema string
+	// Name is a LIKE pattern for the name of the tables, such as order%
+	Name string
+	// Contains is a string the schema and name of the tables joined by a dot must contain
+	Contains string
+	// Types are the types of the tables, one of the TableType constants
+	Types []string
+}
+
+// the tables of the INFORMATION_SCHEMA. TABLES and COLUMNS are keywords of the SHOW
+// and DESCRIBE commands, so they must be quoted
+const (
+	schemataTable = `INFORMATION_SCHEMA.SCHEMATA`
+	tablesTable   = `INFORMATION_SCHEMA."TABLES"`
+	columnsTable  = `INFORMATION_SCHEMA."COLUMNS"`
+	viewsTable    = `INFORMATION_SCHEMA.VIEWS`
+)
+
+// ListSchemas returns the schemas of the catalog
+func ListSchemas(ctx context.Context, db Queryer) ([]Schema, error) {
+	type schema struct {
+		Schema
+		Mutable string `dremio:"IS_MUTABLE"`
+	}
+	q, err := dremiosql.Select("CATALOG_NAME", "SCHEMA_NAME", "SCHEMA_OWNER", "TYPE", "IS_MUTABLE").
+		FromQuoted(schemataTable).
+		OrderBy("SCHEMA_NAME").
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := Select[schema](ctx, db, q)
+	if err != nil {
+		return nil, err
+	}
+	schemas := make([]Schema, len(rows))
+	for i, row := range rows {
+		schemas[i] = row.Schema
+		schemas[i].Mutable = yes(row.Mutable)
+	}
+	return schemas, nil
+}
+
+// ListTables returns the tables matching the filter, ordered by schema and name
+func ListTables(ctx context.Context, db Queryer, filter TableFilter) ([]Table, error) {
+	b := dremiosql.Select("TABLE_CATALOG", "TABLE_SCHEMA", "TABLE_NAME", "TABLE_TYPE").FromQuoted(tablesTable)
+	if filter.Schema != "" {
+		b.WhereEq("TABLE_SCHEMA", filter.Schema)
+	}
+	if filter.Name != "" {
+		b.Where("TABLE_NAME LIKE ?", filter.Name)
+	}
+	if filter.Contains != "" {
+		b.Where("STRPOS(CONCAT(TABLE_SCHEMA, '.', TABLE_NAME), ?) > 0", filter.Contains)
+	}
+	if len(filter.Types) > 0 {
+		args := make([]interface{}, len(filter.Types))
+		for i, t := range filter.Types {
+			args[i] = t
+		}
+		b.Where("TABLE_TYPE IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")+")", args...)
+	}
+	q, err := b.OrderBy("TABLE_SCHEMA").OrderBy("TABLE_NAME").Build()
+	if err != nil {
+		return nil, err
+	}
+	return Select[Table](ctx, db, q)
+}
+
+// DescribeTable returns the description of the table at the path, such as "sales", "2019", "orders".
+// It returns sql.ErrNoRows if there is no such table
+func DescribeTable(ctx context.Context, db Queryer, path ...string) (*TableInfo, error) {
+	if len(path) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	schema := strings.Join(path[:len(path)-1], ".")
+	name := path[len(path)-1]
+	q, err := dremiosql.Select("TABLE_CATALOG", "TABLE_SCHEMA", "TABLE_NAME", "TABLE_TYPE").
+		FromQuoted(tablesTable).
+		WhereEq("TABLE_SCHEMA", schema).
+		WhereEq("TABLE_NAME", name).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	table, err := Get[Table](ctx, db, q)
+	if err != nil {
+		return nil, err
+	}
+	info := &TableInfo{Table: table}
+	type column struct {
+		Column
+		Nullable string `dremio:"IS_NULLABLE"`
+	}
+	q, err = dremiosql.Select("COLUMN_NAME", "ORDINAL_POSITION", "DATA_TYPE", "IS_NULLABLE", "CHARACTER_MAXIMUM_LENGTH", "NUMERIC_PRECISION", "NUMERIC_SCALE", "DATETIME_PRECISION").
+		FromQuoted(columnsTable).
+		WhereEq("TABLE_SCHEMA", schema).
+		WhereEq("TABLE_NAME", name).
+		OrderBy("ORDINAL_POSITION").
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	columns, err := Select[column](ctx, db, q)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range columns {
+		c.Column.Nullable = yes(c.Nullable)
+		info.Columns = append(info.Columns, c.Column)
+	}
+	if table.Type == TableTypeView {
+		q, err = dremiosql.Select("VIEW_DEFINITION").
+			FromQuoted(viewsTable).
+			WhereEq("TABLE_SCHEMA", schema).
+			WhereEq("TABLE_NAME", name).
+			Build()
+		if err != nil {
+			return nil, err
+		}
+		info.ViewDefinition, err = Get[string](ctx, db, q)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// TableColumn is a column listed by ListColumns, along with its table
+type TableColumn struct {
+	Schema string `dremio:"TABLE_SCHEMA"`
+	Table  string `dremio:"TABLE_NAME"`
+	Column
+}
+
+// ListColumns returns the columns of the tables with the name in every schema, ordered by schema
+// and position. Unlike describing each of the tables, it takes a single query
+func ListColumns(ctx context.Context, db Queryer, table string) ([]TableColumn, error) {
+	type column struct {
+		TableColumn
+		Nullable string `dremio:"IS_NULLABLE"`
+	}
+	q, err := dremiosql.Select("TABLE_SCHEMA", "TABLE_NAME", "COLUMN_NAME", "ORDINAL_POSITION", "DATA_TYPE", "IS_NULLABLE", "CHARACTER_MAXIMUM_LENGTH", "NUMERIC_PRECISION", "NUMERIC_SCALE", "DATETIME_PRECISION").
+		FromQuoted(columnsTable).
+		WhereEq("TABLE_NAME", table).
+		OrderBy("TABLE_SCHEMA").
+		OrderBy("ORDINAL_POSITION").
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := Select[column](ctx, db, q)
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]TableColumn, len(rows))
+	for i, row := range rows {
+		columns[i] = row.TableColumn
+		columns[i].Nullable = yes(row.Nullable)
+	}
+	return columns, nil
+}
+
+// yes returns true for the YES of the INFORMATION_SCHEMA boolean columns
+func yes(val string) bool {
+	return strings.EqualFold(val, "YES")
+}
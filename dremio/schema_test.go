@@ -0,0 +1,141 @@
+package dremio_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/pinpt/go-dremio/dremio"
+	"github.com/pinpt/go-dremio/dremiotest"
+	"github.com/pinpt/go-dremio/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSchemaDB(t *testing.T) (*sql.DB, *dremiotest.Server) {
+	srv := dremiotest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.Handle(`FROM INFORMATION_SCHEMA.SCHEMATA`, dremiotest.Result{
+		Columns: []dremiotest.Column{{Name: "CATALOG_NAME"}, {Name: "SCHEMA_NAME"}, {Name: "SCHEMA_OWNER"}, {Name: "TYPE"}, {Name: "IS_MUTABLE"}},
+		Rows: [][]interface{}{
+			{"DREMIO", "INFORMATION_SCHEMA", "<owner>", "SIMPLE", "NO"},
+			{"DREMIO", "sales.2019", "<owner>", "SIMPLE", "YES"},
+		},
+	})
+	tableColumns := []dremiotest.Column{{Name: "TABLE_CATALOG"}, {Name: "TABLE_SCHEMA"}, {Name: "TABLE_NAME"}, {Name: "TABLE_TYPE"}}
+	srv.Handle(`FROM INFORMATION_SCHEMA."TABLES" WHERE TABLE_SCHEMA = 'sales.2019' AND TABLE_NAME = 'orders'`, dremiotest.Result{
+		Columns: tableColumns,
+		Rows:    [][]interface{}{{"DREMIO", "sales.2019", "orders", "VIEW"}},
+	})
+	srv.Handle(`FROM INFORMATION_SCHEMA."TABLES" WHERE TABLE_SCHEMA = `, dremiotest.Result{Columns: tableColumns})
+	srv.Handle(`FROM INFORMATION_SCHEMA."TABLES"`, dremiotest.Result{
+		Columns: tableColumns,
+		Rows: [][]interface{}{
+			{"DREMIO", "sales.2019", "orders", "VIEW"},
+			{"DREMIO", "sales.2019", "select", "TABLE"},
+		},
+	})
+	srv.Handle(`FROM INFORMATION_SCHEMA."COLUMNS" WHERE TABLE_NAME = 'orders'`, dremiotest.Result{
+		Columns: []dremiotest.Column{
+			{Name: "TABLE_SCHEMA"},
+			{Name: "TABLE_NAME"},
+			{Name: "COLUMN_NAME"},
+			{Name: "ORDINAL_POSITION", Type: "INTEGER"},
+			{Name: "DATA_TYPE"},
+			{Name: "IS_NULLABLE"},
+		},
+		Rows: [][]interface{}{
+			{"sales.2019", "orders", "order_id", 1, "BIGINT", "NO"},
+			{"sales.2020", "orders", "order_id", 1, "BIGINT", "YES"},
+		},
+	})
+	srv.Handle(`FROM INFORMATION_SCHEMA."COLUMNS"`, dremiotest.Result{
+		Columns: []dremiotest.Column{
+			{Name: "COLUMN_NAME"},
+			{Name: "ORDINAL_POSITION", Type: "INTEGER"},
+			{Name: "DATA_TYPE"},
+			{Name: "IS_NULLABLE"},
+			{Name: "CHARACTER_MAXIMUM_LENGTH", Type: "INTEGER"},
+			{Name: "NUMERIC_PRECISION", Type: "INTEGER"},
+			{Name: "NUMERIC_SCALE", Type: "INTEGER"},
+			{Name: "DATETIME_PRECISION", Type: "INTEGER"},
+		},
+		Rows: [][]interface{}{
+			{"order_id", 1, "BIGINT", "NO", nil, 64, 0, nil},
+			{"customer", 2, "CHARACTER VARYING", "YES", 65536, nil, nil, nil},
+			{"total", 3, "DECIMAL", "YES", nil, 10, 2, nil},
+		},
+	})
+	srv.Handle(`FROM INFORMATION_SCHEMA.VIEWS`, dremiotest.Result{
+		Columns: []dremiotest.Column{{Name: "VIEW_DEFINITION"}},
+		Rows:    [][]interface{}{{"SELECT * FROM sales.raw"}},
+	})
+	db, err := sql.Open(driver.DriverName, srv.DSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, srv
+}
+
+func TestListSchemas(t *testing.T) {
+	assert := assert.New(t)
+	db, _ := newSchemaDB(t)
+	schemas, err := dremio.ListSchemas(context.Background(), db)
+	assert.NoError(err)
+	assert.Len(schemas, 2)
+	assert.Equal("sales.2019", schemas[1].Name)
+	assert.True(schemas[1].Mutable)
+	assert.False(schemas[0].Mutable)
+}
+
+func TestListTables(t *testing.T) {
+	assert := assert.New(t)
+	db, srv := newSchemaDB(t)
+	tables, err := dremio.ListTables(context.Background(), db, dremio.TableFilter{
+		Name:     "ord%",
+		Contains: "it's",
+		Types:    []string{dremio.TableTypeTable, dremio.TableTypeView},
+	})
+	assert.NoError(err)
+	assert.Len(tables, 2)
+	assert.Equal([]string{"sales", "2019", "orders"}, tables[0].Path())
+	assert.Equal(`sales."2019"."select"`, tables[1].String())
+	queries := srv.Queries()
+	assert.Contains(queries[len(queries)-1], `WHERE TABLE_NAME LIKE 'ord%' AND STRPOS(CONCAT(TABLE_SCHEMA, '.', TABLE_NAME), 'it''s') > 0 AND TABLE_TYPE IN ('TABLE', 'VIEW') ORDER BY TABLE_SCHEMA, TABLE_NAME`)
+}
+
+func TestDescribeTable(t *testing.T) {
+	assert := assert.New(t)
+	db, _ := newSchemaDB(t)
+	ctx := context.Background()
+	info, err := dremio.DescribeTable(ctx, db, "sales", "2019", "orders")
+	assert.NoError(err)
+	assert.Equal(dremio.TableTypeView, info.Type)
+	assert.Equal("SELECT * FROM sales.raw", info.ViewDefinition)
+	assert.Len(info.Columns, 3)
+	assert.Equal("order_id", info.Columns[0].Name)
+	assert.Equal(1, info.Columns[0].Ordinal)
+	assert.False(info.Columns[0].Nullable)
+	assert.True(info.Columns[1].Nullable)
+	assert.Equal(65536, *info.Columns[1].MaxLength)
+	assert.Nil(info.Columns[1].Precision)
+	assert.Equal(10, *info.Columns[2].Precision)
+	assert.Equal(2, *info.Columns[2].Scale)
+
+	_, err = dremio.DescribeTable(ctx, db, "sales", "2019", "missing")
+	assert.Equal(sql.ErrNoRows, err)
+}
+
+func TestListColumns(t *testing.T) {
+	assert := assert.New(t)
+	db, srv := newSchemaDB(t)
+	columns, err := dremio.ListColumns(context.Background(), db, "orders")
+	assert.NoError(err)
+	assert.Len(columns, 2)
+	assert.Equal("sales.2020", columns[1].Schema)
+	assert.Equal("orders", columns[1].Table)
+	assert.Equal("order_id", columns[1].Name)
+	assert.True(columns[1].Nullable)
+	assert.False(columns[0].Nullable)
+	assert.Len(srv.Queries(), 1)
+}
@@ -0,0 +1,51 @@
+package dremio
+
+import (
+	"context"
+	"iter"
+
+	"github.com/pinpt/go-dremio/driver"
+)
+
+// Stream runs the query and returns an iterator over its rows as values of T, scanned like
+// Select. The next page of results is fetched while the current one is read, but no further
+// ahead. Stopping early closes the rows and stops fetching, and cancelling the context while
+// the job is running cancels the job. An error ends the iteration
+//
+//	for order, err := range dremio.Stream[Order](ctx, db, "SELECT * FROM orders") {
+//		if err != nil {
+//			return err
+//		}
+//		// ...
+//	}
+func Stream[T any](ctx context.Context, db Queryer, query string, args ...interface{}) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		ctx, cancel := context.WithCancel(driver.WithPrefetch(ctx))
+		defer cancel()
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer rows.Close()
+		s, err := newScanner[T](rows)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		for rows.Next() {
+			var val T
+			if err := s.scan(rows, &val); err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(val, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
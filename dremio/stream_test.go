@@ -0,0 +1,36 @@
+package dremio_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pinpt/go-dremio/dremio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStream(t *testing.T) {
+	assert := assert.New(t)
+	db := newTestDB(t)
+	ctx := context.Background()
+	var ids []int64
+	for o, err := range dremio.Stream[order](ctx, db, "SELECT * FROM orders") {
+		assert.NoError(err)
+		ids = append(ids, o.ID)
+	}
+	assert.Equal([]int64{1, 2}, ids)
+
+	ids = nil
+	for id, err := range dremio.Stream[int64](ctx, db, "SELECT order_id FROM orders") {
+		assert.NoError(err)
+		ids = append(ids, id)
+		break
+	}
+	assert.Equal([]int64{1}, ids)
+
+	var errs []error
+	for _, err := range dremio.Stream[order](ctx, db, "SELECT * FROM missing") {
+		errs = append(errs, err)
+	}
+	assert.Len(errs, 1)
+	assert.Error(errs[0])
+}
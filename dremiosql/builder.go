@@ -0,0 +1,164 @@
+package dremiosql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SelectBuilder builds a SELECT query with quoted identifiers and literals
+type SelectBuilder struct {
+	columns []string
+	from    string
+	where   []string
+	orderBy []string
+	limit   int
+	offset  int
+	err     error
+}
+
+// Select starts a query for the columns, or all of them if there are none. a column named * isn't quoted
+func Select(columns ...string) *SelectBuilder {
+	b := &SelectBuilder{}
+	for _, column := range columns {
+		if column == "*" {
+			b.columns = append(b.columns, column)
+		} else {
+			b.columns = append(b.columns, QuoteIdentifier(column))
+		}
+	}
+	return b
+}
+
+// From sets the path of the dataset to query
+func (b *SelectBuilder) From(path ...string) *SelectBuilder {
+	b.from = QuotePath(path...)
+	return b
+}
+
+// FromQuoted sets the path of the dataset to query, which is already quoted. use it for names
+// which must be quoted even though they aren't reserved, such as INFORMATION_SCHEMA."TABLES"
+func (b *SelectBuilder) FromQuoted(path string) *SelectBuilder {
+	b.from = path
+	return b
+}
+
+// Where adds a condition, which is combined with the others using AND. each ? in the condition
+// outside of quotes is replaced by the next arg as a literal
+func (b *SelectBuilder) Where(cond string, args ...interface{}) *SelectBuilder {
+	cond, err := bind(cond, args)
+	if err != nil {
+		if b.err == nil {
+			b.err = err
+		}
+		return b
+	}
+	b.where = append(b.where, cond)
+	return b
+}
+
+// WhereEq adds a condition that the column is equal to the value, or is null for a nil value
+func (b *SelectBuilder) WhereEq(column string, value interface{}) *SelectBuilder {
+	if value == nil {
+		return b.Where(QuoteIdentifier(column) + " IS NULL")
+	}
+	return b.Where(QuoteIdentifier(column)+" = ?", value)
+}
+
+// OrderBy adds a column to sort by in ascending order
+func (b *SelectBuilder) OrderBy(column string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, QuoteIdentifier(column))
+	return b
+}
+
+// OrderByDesc adds a column to sort by in descending order
+func (b *SelectBuilder) OrderByDesc(column string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, QuoteIdentifier(column)+" DESC")
+	return b
+}
+
+// Limit sets the maximum number of rows to return
+func (b *SelectBuilder) Limit(n int) *SelectBuilder {
+	b.limit = n
+	return b
+}
+
+// Offset sets the number of rows to skip
+func (b *SelectBuilder) Offset(n int) *SelectBuilder {
+	b.offset = n
+	return b
+}
+
+// Build returns the query or the first error adding to it
+func (b *SelectBuilder) Build() (string, error) {
+	if b.err != nil {
+		return "", b.err
+	}
+	if b.from == "" {
+		return "", fmt.Errorf("missing dataset to select from")
+	}
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	if len(b.columns) == 0 {
+		sb.WriteString("*")
+	} else {
+		sb.WriteString(strings.Join(b.columns, ", "))
+	}
+	sb.WriteString(" FROM ")
+	sb.WriteString(b.from)
+	if len(b.where) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(b.where, " AND "))
+	}
+	if len(b.orderBy) > 0 {
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(strings.Join(b.orderBy, ", "))
+	}
+	if b.limit > 0 {
+		sb.WriteString(" LIMIT " + strconv.Itoa(b.limit))
+	}
+	if b.offset > 0 {
+		sb.WriteString(" OFFSET " + strconv.Itoa(b.offset))
+	}
+	return sb.String(), nil
+}
+
+// String returns the query, or an empty string if there was an error building it
+func (b *SelectBuilder) String() string {
+	q, _ := b.Build()
+	return q
+}
+
+// bind replaces the placeholders in the condition with the args as literals
+func bind(cond string, args []interface{}) (string, error) {
+	var sb strings.Builder
+	var quote byte
+	var index int
+	for i := 0; i < len(cond); i++ {
+		c := cond[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			if index >= len(args) {
+				return "", fmt.Errorf("missing arg for placeholder %d in %s", index+1, cond)
+			}
+			lit, err := Literal(args[index])
+			if err != nil {
+				return "", err
+			}
+			index++
+			sb.WriteString(lit)
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	if index != len(args) {
+		return "", fmt.Errorf("expected %d args for %s but got %d", index, cond, len(args))
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,35 @@
+package dremiosql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectBuilder(t *testing.T) {
+	assert := assert.New(t)
+	q, err := Select("order_id", "value").
+		From("sales", "2019", "orders").
+		Where("customer = ? AND note <> '?'", "o'neil").
+		WhereEq("paid", true).
+		WhereEq("refunded", nil).
+		OrderByDesc("value").
+		OrderBy("order_id").
+		Limit(10).
+		Offset(20).
+		Build()
+	assert.NoError(err)
+	assert.Equal(`SELECT order_id, "value" FROM sales."2019".orders WHERE customer = 'o''neil' AND note <> '?' AND paid = TRUE AND refunded IS NULL ORDER BY "value" DESC, order_id LIMIT 10 OFFSET 20`, q)
+	assert.Equal("SELECT * FROM orders", Select().From("orders").String())
+	assert.Equal("SELECT * FROM orders", Select("*").From("orders").String())
+	assert.Equal(`SELECT * FROM INFORMATION_SCHEMA."TABLES"`, Select().FromQuoted(`INFORMATION_SCHEMA."TABLES"`).String())
+
+	_, err = Select().From("orders").Where("a = ? AND b = ?", 1).Build()
+	assert.Error(err)
+	_, err = Select().From("orders").Where("a = ?", 1, 2).Build()
+	assert.Error(err)
+	_, err = Select().From("orders").Where("a = ?", struct{}{}).Build()
+	assert.Error(err)
+	_, err = Select("a").Build()
+	assert.Error(err)
+}
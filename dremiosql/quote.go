@@ -0,0 +1,114 @@
+// Package dremiosql quotes identifiers and values for Dremio SQL and builds simple queries from them,
+// so that table names and values don't have to be concatenated into queries by hand.
+//
+//	query, err := dremiosql.Select("order_id", "total").
+//		From("sales", "2019", "orders").
+//		Where("customer = ?", customer).
+//		OrderByDesc("total").
+//		Limit(10).
+//		Build()
+//	// SELECT order_id, total FROM sales."2019".orders WHERE customer = 'acme' ORDER BY total DESC LIMIT 10
+package dremiosql
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var plainIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// QuoteIdentifier returns the name as an identifier, surrounded in double quotes if it is a reserved word
+// or isn't a plain identifier, such as a name starting with a digit or containing a dot or a quote
+func QuoteIdentifier(name string) string {
+	if plainIdentifier.MatchString(name) && !IsReserved(name) {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuotePath returns the parts of a path, such as a space, its folders and a dataset, as identifiers
+// joined by dots
+func QuotePath(parts ...string) string {
+	quoted := make([]string, len(parts))
+	for i, part := range parts {
+		quoted[i] = QuoteIdentifier(part)
+	}
+	return strings.Join(quoted, ".")
+}
+
+// SplitPath splits a path such as space.folder."dataset.json" into its parts, removing their quotes
+func SplitPath(path string) ([]string, error) {
+	var parts []string
+	var part strings.Builder
+	var quoted, wasQuoted bool
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case quoted && c == '"':
+			if i+1 < len(path) && path[i+1] == '"' {
+				part.WriteByte('"')
+				i++
+			} else {
+				quoted = false
+			}
+		case quoted:
+			part.WriteByte(c)
+		case c == '"':
+			if part.Len() > 0 {
+				return nil, fmt.Errorf("unexpected quote at %d in path %s", i, path)
+			}
+			quoted, wasQuoted = true, true
+		case c == '.':
+			if part.Len() == 0 && !wasQuoted {
+				return nil, fmt.Errorf("empty part at %d in path %s", i, path)
+			}
+			parts = append(parts, part.String())
+			part.Reset()
+			wasQuoted = false
+		default:
+			if wasQuoted {
+				return nil, fmt.Errorf("unexpected character at %d in path %s", i, path)
+			}
+			part.WriteByte(c)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in path %s", path)
+	}
+	if part.Len() == 0 && !wasQuoted {
+		return nil, fmt.Errorf("empty part at the end of path %s", path)
+	}
+	return append(parts, part.String()), nil
+}
+
+// QuoteLiteral returns the string as a literal, surrounded in single quotes
+func QuoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// Literal returns the value as a literal. It supports nil, strings, booleans, numbers and times
+func Literal(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		return QuoteLiteral(val), nil
+	case bool:
+		if val {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(val), nil
+	case float32:
+		return strconv.FormatFloat(float64(val), 'g', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64), nil
+	case time.Time:
+		return "TIMESTAMP '" + val.UTC().Format("2006-01-02 15:04:05.000") + "'", nil
+	}
+	return "", fmt.Errorf("cannot use %T as a literal", v)
+}
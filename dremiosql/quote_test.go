@@ -0,0 +1,55 @@
+package dremiosql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("orders", QuoteIdentifier("orders"))
+	assert.Equal(`"select"`, QuoteIdentifier("select"))
+	assert.Equal(`"2019"`, QuoteIdentifier("2019"))
+	assert.Equal(`"orders.json"`, QuoteIdentifier("orders.json"))
+	assert.Equal(`"say ""hi"""`, QuoteIdentifier(`say "hi"`))
+	assert.Equal(`""`, QuoteIdentifier(""))
+	assert.Equal(`sales."2019"."orders.json"`, QuotePath("sales", "2019", "orders.json"))
+}
+
+func TestSplitPath(t *testing.T) {
+	assert := assert.New(t)
+	parts, err := SplitPath(`sales."2019"."orders.json"`)
+	assert.NoError(err)
+	assert.Equal([]string{"sales", "2019", "orders.json"}, parts)
+	parts, err = SplitPath(QuotePath("a", `b "c".d`))
+	assert.NoError(err)
+	assert.Equal([]string{"a", `b "c".d`}, parts)
+	parts, err = SplitPath("orders")
+	assert.NoError(err)
+	assert.Equal([]string{"orders"}, parts)
+	for _, path := range []string{"", "a..b", "a.", `a."b`, `a."b"c`, `a.b"c"`} {
+		_, err := SplitPath(path)
+		assert.Error(err, path)
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(`'it''s'`, QuoteLiteral("it's"))
+	for v, expected := range map[interface{}]string{
+		nil:      "NULL",
+		"x":      "'x'",
+		true:     "TRUE",
+		int64(3): "3",
+		1.5:      "1.5",
+		time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC): "TIMESTAMP '2019-01-02 03:04:05.000'",
+	} {
+		lit, err := Literal(v)
+		assert.NoError(err)
+		assert.Equal(expected, lit)
+	}
+	_, err := Literal([]string{"a"})
+	assert.Error(err)
+}
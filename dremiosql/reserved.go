@@ -1,33 +1,18 @@
-package console
+package dremiosql
 
-import (
-	"regexp"
-	"strings"
-)
+import "strings"
 
-// Word returns a dremio-friendly word
-func Word(str string) string {
-	if ok := reserverWords[strings.ToUpper(str)]; ok || regexp.MustCompile(`^\d`).MatchString(str) {
-		return `"` + str + `"`
-	}
-	return str
-}
-
-// JoinWords similar to strings.Join but surrounding reserved words in double quotes
-func JoinWords(words []string, deli string) string {
-	str := make([]string, len(words))
-	for i, w := range words {
-		str[i] = Word(w)
-	}
-	return strings.Join(str, deli)
+// IsReserved returns true if the word is reserved by Dremio and must be quoted to be used as an identifier
+func IsReserved(word string) bool {
+	return reservedWords[strings.ToUpper(word)]
 }
 
 // https://docs.dremio.com/sql-reference/reserved-keywords.html
-// ReserverWords Following reserved words can only be used with surrounding double quotes " in Dremio.
-var reserverWords map[string]bool
+// reservedWords can only be used as identifiers with surrounding double quotes " in Dremio.
+var reservedWords map[string]bool
 
 func init() {
-	reserverWords = map[string]bool{
+	reservedWords = map[string]bool{
 		"ABS":      true,
 		"ALL":      true,
 		"ALLOCATE": true,
@@ -39,62 +24,62 @@ func init() {
 		"ARRAY":    true,
 
 		"ARRAY_MAX_CARDINALITY": true,
-		"AS":               true,
-		"ASENSITIVE":       true,
-		"ASYMMETRIC":       true,
-		"AT":               true,
-		"ATOMIC":           true,
-		"AUTHORIZATION":    true,
-		"AVG":              true,
-		"BEGIN":            true,
-		"BEGIN_FRAME":      true,
-		"BEGIN_PARTITION":  true,
-		"BETWEEN":          true,
-		"BIGINT":           true,
-		"BINARY":           true,
-		"BIT":              true,
-		"BLOB":             true,
-		"BOOLEAN":          true,
-		"BOTH":             true,
-		"BY":               true,
-		"CALL":             true,
-		"CALLED":           true,
-		"CARDINALITY":      true,
-		"CASCADED":         true,
-		"CASE":             true,
-		"CAST":             true,
-		"CEIL":             true,
-		"CEILING":          true,
-		"CHAR":             true,
-		"CHAR_LENGTH":      true,
-		"CHARACTER":        true,
-		"CHARACTER_LENGTH": true,
-		"CHECK":            true,
-		"CLASSIFIER":       true,
-		"CLOB":             true,
-		"CLOSE":            true,
-		"COALESCE":         true,
-		"COLLATE":          true,
-		"COLLECT":          true,
-		"COLUMN":           true,
-		"COMMIT":           true,
-		"CONDITION":        true,
-		"CONNECT":          true,
-		"CONSTRAINT":       true,
-		"CONTAINS":         true,
-		"CONVERT":          true,
-		"CORR":             true,
-		"CORRESPONDING":    true,
-		"COUNT":            true,
-		"COVAR_POP":        true,
-		"COVAR_SAMP":       true,
-		"CREATE":           true,
-		"CROSS":            true,
-		"CUBE":             true,
-		"CUME_DIST":        true,
-		"CURRENT":          true,
-		"CURRENT_CATALOG":  true,
-		"CURRENT_DATE":     true,
+		"AS":                    true,
+		"ASENSITIVE":            true,
+		"ASYMMETRIC":            true,
+		"AT":                    true,
+		"ATOMIC":                true,
+		"AUTHORIZATION":         true,
+		"AVG":                   true,
+		"BEGIN":                 true,
+		"BEGIN_FRAME":           true,
+		"BEGIN_PARTITION":       true,
+		"BETWEEN":               true,
+		"BIGINT":                true,
+		"BINARY":                true,
+		"BIT":                   true,
+		"BLOB":                  true,
+		"BOOLEAN":               true,
+		"BOTH":                  true,
+		"BY":                    true,
+		"CALL":                  true,
+		"CALLED":                true,
+		"CARDINALITY":           true,
+		"CASCADED":              true,
+		"CASE":                  true,
+		"CAST":                  true,
+		"CEIL":                  true,
+		"CEILING":               true,
+		"CHAR":                  true,
+		"CHAR_LENGTH":           true,
+		"CHARACTER":             true,
+		"CHARACTER_LENGTH":      true,
+		"CHECK":                 true,
+		"CLASSIFIER":            true,
+		"CLOB":                  true,
+		"CLOSE":                 true,
+		"COALESCE":              true,
+		"COLLATE":               true,
+		"COLLECT":               true,
+		"COLUMN":                true,
+		"COMMIT":                true,
+		"CONDITION":             true,
+		"CONNECT":               true,
+		"CONSTRAINT":            true,
+		"CONTAINS":              true,
+		"CONVERT":               true,
+		"CORR":                  true,
+		"CORRESPONDING":         true,
+		"COUNT":                 true,
+		"COVAR_POP":             true,
+		"COVAR_SAMP":            true,
+		"CREATE":                true,
+		"CROSS":                 true,
+		"CUBE":                  true,
+		"CUME_DIST":             true,
+		"CURRENT":               true,
+		"CURRENT_CATALOG":       true,
+		"CURRENT_DATE":          true,
 
 		"CURRENT_DEFAULT_TRANSFORM_GROUP": true,
 
@@ -0,0 +1,277 @@
+// Package dremiotest provides an in-process fake of the REST API of a Dremio coordinator,
+// to unit test code using the dremio driver without a real cluster.
+//
+//	srv := dremiotest.NewServer()
+//	defer srv.Close()
+//	srv.Handle(`FROM orders`, dremiotest.Result{
+//		Columns: []dremiotest.Column{{Name: "id", Type: "BIGINT"}},
+//		Rows:    [][]interface{}{{1}, {2}},
+//	})
+//	db, err := sql.Open("dremio", srv.DSN())
+package dremiotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The credentials accepted by the server, used by DSN
+const (
+	Username = "dremio"
+	Password = "dremio123"
+)
+
+const token = "dremiotest"
+
+// Column is a column of a result
+type Column struct {
+	Name string
+	// Type is the name of the Dremio type of the column, such as VARCHAR or BIGINT. defaults to VARCHAR
+	Type string
+}
+
+// Result is the canned result of the queries matching a pattern
+type Result struct {
+	Columns []Column
+	// Rows are the values of each row, in the order of the columns
+	Rows [][]interface{}
+	// States are the states the job goes through before it completes or fails, such as
+	// ENQUEUED or RUNNING. each state is returned by one poll of the job
+	States []string
+	// Error fails the job with the message, such as "RESOURCE ERROR: out of memory"
+	Error string
+}
+
+// Server is a fake Dremio coordinator. It implements login, query submission,
+// job status and paged results. Queries return the result of the first pattern
+// registered with Handle which matches their SQL, other queries fail with a
+// VALIDATION ERROR
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	handlers []handler
+	jobs     map[string]*job
+	queries  []string
+	faults   []*fault
+}
+
+type handler struct {
+	pattern *regexp.Regexp
+	result  Result
+}
+
+type job struct {
+	sql    string
+	result Result
+	polls  int
+	state  string
+}
+
+type fault struct {
+	path   string
+	status int
+	times  int
+}
+
+// NewServer starts and returns a new Server. Close it when done
+func NewServer() *Server {
+	s := &Server{jobs: make(map[string]*job)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// DSN returns the data source name to open the driver with
+func (s *Server) DSN() string {
+	u, _ := url.Parse(s.URL)
+	u.User = url.UserPassword(Username, Password)
+	return u.String()
+}
+
+// Handle registers the result of the queries with SQL matching the pattern, a regular expression
+func (s *Server) Handle(pattern string, r Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, handler{regexp.MustCompile(pattern), r})
+}
+
+// Fail makes the next requests with the path, such as /api/v3/sql, fail with the status code
+func (s *Server) Fail(path string, status int, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault{path, status, times})
+}
+
+// Queries returns the SQL of every query submitted, in order
+func (s *Server) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.queries...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range s.faults {
+		if f.times > 0 && f.path == r.URL.Path {
+			f.times--
+			w.WriteHeader(f.status)
+			return
+		}
+	}
+	if r.URL.Path == "/apiv2/login" {
+		s.login(w, r)
+		return
+	}
+	if r.Header.Get("Authorization") != "_dremio"+token {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"errorMessage": "unauthorized"})
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/api/v3/")
+	parts := strings.Split(path, "/")
+	switch {
+	case path == "sql" && r.Method == http.MethodPost:
+		s.submit(w, r)
+	case len(parts) == 2 && parts[0] == "job" && r.Method == http.MethodGet:
+		s.status(w, parts[1])
+	case len(parts) == 3 && parts[0] == "job" && parts[2] == "results" && r.Method == http.MethodGet:
+		s.results(w, r, parts[1])
+	case len(parts) == 3 && parts[0] == "job" && parts[2] == "cancel" && r.Method == http.MethodPost:
+		s.cancel(w, parts[1])
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"errorMessage": "not found"})
+	}
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	var creds struct {
+		UserName string `json:"userName"`
+		Password string `json:"password"`
+	}
+	json.NewDecoder(r.Body).Decode(&creds)
+	if creds.UserName != Username || creds.Password != Password {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"errorMessage": "Invalid username or password"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"token": token})
+}
+
+func (s *Server) submit(w http.ResponseWriter, r *http.Request) {
+	var q struct {
+		SQL string `json:"sql"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"errorMessage": err.Error()})
+		return
+	}
+	s.queries = append(s.queries, q.SQL)
+	j := &job{sql: q.SQL}
+	matched := false
+	for _, h := range s.handlers {
+		if h.pattern.MatchString(q.SQL) {
+			j.result = h.result
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		j.result.Error = "VALIDATION ERROR: no result registered for " + q.SQL
+	}
+	id := strconv.Itoa(len(s.jobs) + 1)
+	s.jobs[id] = j
+	writeJSON(w, http.StatusOK, map[string]string{"id": id})
+}
+
+func (s *Server) status(w http.ResponseWriter, id string) {
+	j := s.jobs[id]
+	if j == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"errorMessage": "job not found"})
+		return
+	}
+	if j.state != "CANCELED" {
+		j.state = "COMPLETED"
+		if j.result.Error != "" {
+			j.state = "FAILED"
+		}
+		if j.polls < len(j.result.States) {
+			j.state = j.result.States[j.polls]
+		}
+		j.polls++
+	}
+	status := map[string]interface{}{"jobState": j.state, "rowCount": len(j.result.Rows)}
+	if j.state == "FAILED" {
+		status["errorMessage"] = j.result.Error
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) results(w http.ResponseWriter, r *http.Request, id string) {
+	j := s.jobs[id]
+	if j == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"errorMessage": "job not found"})
+		return
+	}
+	if j.state != "COMPLETED" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"errorMessage": fmt.Sprintf("job is %s", j.state)})
+		return
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	type schemaType struct {
+		Name string `json:"name"`
+	}
+	type schema struct {
+		Name string     `json:"name"`
+		Type schemaType `json:"type"`
+	}
+	cols := make([]schema, len(j.result.Columns))
+	for i, c := range j.result.Columns {
+		cols[i] = schema{Name: c.Name, Type: schemaType{Name: c.Type}}
+		if c.Type == "" {
+			cols[i].Type.Name = "VARCHAR"
+		}
+	}
+	rows := make([]map[string]interface{}, 0)
+	for i := offset; i < offset+limit && i < len(j.result.Rows); i++ {
+		row := make(map[string]interface{})
+		for c, val := range j.result.Rows[i] {
+			if c < len(cols) {
+				row[cols[c].Name] = val
+			}
+		}
+		rows = append(rows, row)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"rowCount": len(j.result.Rows),
+		"schema":   cols,
+		"rows":     rows,
+	})
+}
+
+func (s *Server) cancel(w http.ResponseWriter, id string) {
+	j := s.jobs[id]
+	if j == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"errorMessage": "job not found"})
+		return
+	}
+	j.state = "CANCELED"
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	buf, _ := json.Marshal(v)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	io.WriteString(w, string(buf))
+}
@@ -0,0 +1,61 @@
+package dremiotest_test
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/pinpt/go-dremio/dremiotest"
+	"github.com/pinpt/go-dremio/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer(t *testing.T) {
+	assert := assert.New(t)
+	srv := dremiotest.NewServer()
+	defer srv.Close()
+	srv.Handle(`FROM orders`, dremiotest.Result{
+		Columns: []dremiotest.Column{{Name: "id", Type: "BIGINT"}, {Name: "name"}},
+		Rows:    [][]interface{}{{1, "a"}, {2, "b"}, {3, nil}},
+		States:  []string{"RUNNING"},
+	})
+	srv.Handle(`FROM missing`, dremiotest.Result{Error: "VALIDATION ERROR: Object 'missing' not found"})
+	srv.Fail("/api/v3/sql", http.StatusServiceUnavailable, 1)
+
+	db, err := sql.Open(driver.DriverName, srv.DSN()+"?pagesize=2&retrybackoff=1ms&resubmit=true")
+	assert.NoError(err)
+	defer db.Close()
+	rows, err := db.Query("SELECT id, name FROM orders WHERE id > ?", 0)
+	assert.NoError(err)
+	var ids []int64
+	var names []sql.NullString
+	for rows.Next() {
+		var id int64
+		var name sql.NullString
+		assert.NoError(rows.Scan(&id, &name))
+		ids = append(ids, id)
+		names = append(names, name)
+	}
+	assert.NoError(rows.Err())
+	assert.NoError(rows.Close())
+	assert.Equal([]int64{1, 2, 3}, ids)
+	assert.Equal(sql.NullString{String: "b", Valid: true}, names[1])
+	assert.False(names[2].Valid)
+
+	_, err = db.Query("SELECT * FROM missing")
+	var jerr *driver.JobError
+	assert.True(errors.As(err, &jerr))
+	assert.Equal("VALIDATION", jerr.Category)
+	assert.Equal([]string{"SELECT id, name FROM orders WHERE id >  0  ", "SELECT * FROM missing"}, srv.Queries())
+}
+
+func TestServerLogin(t *testing.T) {
+	assert := assert.New(t)
+	srv := dremiotest.NewServer()
+	defer srv.Close()
+	db, err := sql.Open(driver.DriverName, "http://dremio:wrong@"+srv.Listener.Addr().String())
+	assert.NoError(err)
+	defer db.Close()
+	assert.EqualError(db.Ping(), "error during login: Invalid username or password")
+}
@@ -0,0 +1,189 @@
+package dremiotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const redacted = "REDACTED"
+
+// Interaction is a request to a coordinator and its response, as saved in a fixture file
+type Interaction struct {
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Body     string `json:"body,omitempty"`
+	Status   int    `json:"status"`
+	Response string `json:"response"`
+}
+
+func (i *Interaction) key() string {
+	if i.Path == "/apiv2/login" {
+		// the credentials are redacted, any login matches
+		return i.Method + " " + i.Path
+	}
+	return i.Method + " " + i.Path + " " + i.Body
+}
+
+// Recorder is an http.RoundTripper which records the interactions with a coordinator,
+// use it as the transport of Config.HTTPClient and Save the fixture once done. The password
+// of the login and the tokens are redacted
+type Recorder struct {
+	// Transport sends the requests. defaults to http.DefaultTransport
+	Transport http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+}
+
+var _ http.RoundTripper = (*Recorder)(nil)
+
+// NewRecorder returns a Recorder sending the requests with the transport
+func NewRecorder(transport http.RoundTripper) *Recorder {
+	return &Recorder{Transport: transport}
+}
+
+// RoundTrip sends the request and records it with its response
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	rbuf, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(rbuf))
+	i := Interaction{
+		Method:   req.Method,
+		Path:     req.URL.RequestURI(),
+		Body:     string(body),
+		Status:   resp.StatusCode,
+		Response: string(rbuf),
+	}
+	if req.URL.Path == "/apiv2/login" {
+		i.Body = redact(i.Body, "password")
+		i.Response = redact(i.Response, "token")
+	}
+	r.mu.Lock()
+	r.interactions = append(r.interactions, i)
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// redact replaces the value of the field of a JSON object
+func redact(body string, field string) string {
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &obj); err != nil {
+		return body
+	}
+	if _, ok := obj[field]; ok {
+		obj[field] = redacted
+	}
+	buf, _ := json.Marshal(obj)
+	return string(buf)
+}
+
+// Interactions returns the interactions recorded so far
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.interactions...)
+}
+
+// Save writes the recorded interactions to a fixture file
+func (r *Recorder) Save(filename string) error {
+	buf, err := json.MarshalIndent(r.Interactions(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, buf, 0644)
+}
+
+// Replayer is an http.RoundTripper which serves the interactions of a fixture file without
+// any network. Requests are matched by method, path and body, regardless of the host. When
+// the same request was recorded several times, such as when polling the state of a job,
+// the responses are served in the order they were recorded and the last one is repeated
+type Replayer struct {
+	mu        sync.Mutex
+	responses map[string][]Interaction
+}
+
+var _ http.RoundTripper = (*Replayer)(nil)
+
+// NewReplayer returns a Replayer serving the interactions
+func NewReplayer(interactions []Interaction) *Replayer {
+	r := &Replayer{responses: make(map[string][]Interaction)}
+	for _, i := range interactions {
+		r.responses[i.key()] = append(r.responses[i.key()], i)
+	}
+	return r
+}
+
+// LoadReplayer returns a Replayer serving the interactions of the fixture file
+func LoadReplayer(filename string) (*Replayer, error) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var interactions []Interaction
+	if err := json.Unmarshal(buf, &interactions); err != nil {
+		return nil, fmt.Errorf("error decoding fixture %s. %v", filename, err)
+	}
+	return NewReplayer(interactions), nil
+}
+
+// RoundTrip returns the recorded response of the request
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	i := Interaction{Method: req.Method, Path: req.URL.RequestURI()}
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		i.Body = string(body)
+	}
+	r.mu.Lock()
+	key := i.key()
+	responses := r.responses[key]
+	if len(responses) == 0 {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("no recorded interaction for %s %s", i.Method, i.Path)
+	}
+	found := responses[0]
+	if len(responses) > 1 {
+		r.responses[key] = responses[1:]
+	}
+	r.mu.Unlock()
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", found.Status, http.StatusText(found.Status)),
+		StatusCode:    found.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(strings.NewReader(found.Response)),
+		ContentLength: int64(len(found.Response)),
+		Request:       req,
+	}, nil
+}
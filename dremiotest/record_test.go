@@ -0,0 +1,66 @@
+package dremiotest_test
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/pinpt/go-dremio/dremiotest"
+	"github.com/pinpt/go-dremio/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func readIDs(t *testing.T, cfg *driver.Config) []int64 {
+	c, err := driver.NewConnector(*cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(c)
+	defer db.Close()
+	rows, err := db.Query("SELECT id FROM orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		rows.Scan(&id)
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestRecordReplay(t *testing.T) {
+	assert := assert.New(t)
+	srv := dremiotest.NewServer()
+	srv.Handle(`FROM orders`, dremiotest.Result{
+		Columns: []dremiotest.Column{{Name: "id", Type: "BIGINT"}},
+		Rows:    [][]interface{}{{1}, {2}, {3}},
+		States:  []string{"RUNNING"},
+	})
+	cfg, err := driver.ParseDSN(srv.DSN() + "?pagesize=2")
+	assert.NoError(err)
+	recorder := dremiotest.NewRecorder(nil)
+	cfg.HTTPClient = &http.Client{Transport: recorder}
+	assert.Equal([]int64{1, 2, 3}, readIDs(t, cfg))
+	srv.Close()
+
+	fixture := filepath.Join(t.TempDir(), "orders.json")
+	assert.NoError(recorder.Save(fixture))
+	buf, err := ioutil.ReadFile(fixture)
+	assert.NoError(err)
+	assert.NotContains(string(buf), dremiotest.Password)
+	assert.Contains(string(buf), "REDACTED")
+
+	// the server is gone, everything comes from the fixture
+	replayer, err := dremiotest.LoadReplayer(fixture)
+	assert.NoError(err)
+	cfg.HTTPClient = &http.Client{Transport: replayer}
+	assert.Equal([]int64{1, 2, 3}, readIDs(t, cfg))
+}
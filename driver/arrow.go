@@ -0,0 +1,294 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// QueryArrow runs the query on a connection of the db and returns its results as Arrow
+// record batches instead of rows. With the REST API each page of results becomes a record
+// batch built using the schema of the job, with Arrow Flight the record batches are passed
+// through as is. The reader outlives the pooled connections of the db, so with the REST API it
+// uses the connection the connector shares with jobs and the catalog, and with Arrow Flight it
+// opens a client of its own, which is closed when the reader is released
+func QueryArrow(ctx context.Context, db *sql.DB, query string, args ...interface{}) (array.RecordReader, error) {
+	named, err := convertArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var connector *Connector
+	err = conn.Raw(func(dc interface{}) error {
+		c, ok := dc.(*connection)
+		if !ok {
+			return fmt.Errorf("expected a dremio connection but was %T", dc)
+		}
+		connector = c.connector
+		return nil
+	})
+	conn.Close()
+	if err != nil {
+		return nil, err
+	}
+	if !isFlight(connector.cfg.Proto) {
+		c, err := connector.restConnection(ctx, "Arrow queries")
+		if err != nil {
+			return nil, err
+		}
+		return c.queryArrow(ctx, query, named)
+	}
+	dc, err := connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := dc.(*connection)
+	reader, err := c.queryArrow(ctx, query, named)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	reader.release = func() { c.Close() }
+	return reader, nil
+}
+
+func (c *connection) queryArrow(ctx context.Context, rawQuery string, args []driver.NamedValue) (*recordReader, error) {
+	q := c.newQuery(ctx, rawQuery, args)
+	if c.flight != nil {
+		rows, err := c.flight.query(ctx, c, q)
+		if err != nil {
+			return nil, err
+		}
+		frows := rows.(*flightRows)
+		return newRecordReader(frows.schema, &flightBatches{frows}), nil
+	}
+	dr, err := q.queryNamed(ctx, c, args)
+	if err != nil {
+		return nil, err
+	}
+	r := dr.(*rows)
+	return newRecordReader(arrowSchema(r.parent.schema), &restBatches{rows: r}), nil
+}
+
+// batches produces the record batches of a query, returning nil when there are no more
+type batches interface {
+	next(schema *arrow.Schema) (arrow.Record, error)
+	close()
+}
+
+// recordReader is an array.RecordReader over the batches of a query
+type recordReader struct {
+	refs    int64
+	schema  *arrow.Schema
+	batches batches
+	cur     arrow.Record
+	err     error
+	release func()
+}
+
+var _ array.RecordReader = (*recordReader)(nil)
+
+func newRecordReader(schema *arrow.Schema, b batches) *recordReader {
+	return &recordReader{refs: 1, schema: schema, batches: b}
+}
+
+func (r *recordReader) Retain() {
+	atomic.AddInt64(&r.refs, 1)
+}
+
+func (r *recordReader) Release() {
+	if atomic.AddInt64(&r.refs, -1) == 0 {
+		if r.cur != nil {
+			r.cur.Release()
+			r.cur = nil
+		}
+		r.batches.close()
+		if r.release != nil {
+			r.release()
+		}
+	}
+}
+
+func (r *recordReader) Schema() *arrow.Schema {
+	return r.schema
+}
+
+func (r *recordReader) Next() bool {
+	if r.cur != nil {
+		r.cur.Release()
+		r.cur = nil
+	}
+	if r.err != nil {
+		return false
+	}
+	r.cur, r.err = r.batches.next(r.schema)
+	return r.cur != nil
+}
+
+func (r *recordReader) RecordBatch() arrow.RecordBatch {
+	return r.cur
+}
+
+func (r *recordReader) Record() arrow.Record {
+	return r.cur
+}
+
+func (r *recordReader) Err() error {
+	return r.err
+}
+
+type flightBatches struct {
+	rows *flightRows
+}
+
+func (b *flightBatches) next(schema *arrow.Schema) (arrow.Record, error) {
+	ok, err := b.rows.nextRecord()
+	if err != nil {
+		b.rows.complete(err)
+		return nil, err
+	}
+	if !ok {
+		b.rows.complete(nil)
+		return nil, nil
+	}
+	rec := b.rows.record
+	b.rows.read += int(rec.NumRows())
+	// the record belongs to the stream reader, keep it until the caller is done with it
+	rec.Retain()
+	return rec, nil
+}
+
+func (b *flightBatches) close() {
+	b.rows.Close()
+}
+
+type restBatches struct {
+	rows *rows
+}
+
+func (b *restBatches) next(schema *arrow.Schema) (arrow.Record, error) {
+	page, err := b.rows.nextPage()
+	if err != nil || page == nil {
+		return nil, err
+	}
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	for _, row := range page {
+		for i, f := range schema.Fields() {
+			if err := appendJSONValue(builder.Field(i), row[f.Name]); err != nil {
+				return nil, fmt.Errorf("error converting column %s. %v", f.Name, err)
+			}
+		}
+	}
+	return builder.NewRecord(), nil
+}
+
+func (b *restBatches) close() {
+	b.rows.Close()
+}
+
+// arrowSchema returns the arrow schema for the schema of a job. types without
+// an arrow equivalent, such as STRUCT or LIST, are returned as JSON strings
+func arrowSchema(s []schema) *arrow.Schema {
+	fields := make([]arrow.Field, len(s))
+	for i, col := range s {
+		fields[i] = arrow.Field{Name: col.Name, Type: arrowType(col.Type), Nullable: true}
+	}
+	return arrow.NewSchema(fields, nil)
+}
+
+func arrowType(t schemaType) arrow.DataType {
+	switch t.Name {
+	case "BOOLEAN":
+		return arrow.FixedWidthTypes.Boolean
+	case "TINYINT", "SMALLINT", "INTEGER":
+		return arrow.PrimitiveTypes.Int32
+	case "BIGINT":
+		return arrow.PrimitiveTypes.Int64
+	case "FLOAT":
+		return arrow.PrimitiveTypes.Float32
+	case "DOUBLE":
+		return arrow.PrimitiveTypes.Float64
+	case "DECIMAL":
+		precision := t.Precision
+		if precision == 0 {
+			precision = 38
+		}
+		return &arrow.Decimal128Type{Precision: precision, Scale: t.Scale}
+	case "VARBINARY", "BINARY":
+		return arrow.BinaryTypes.Binary
+	case "DATE":
+		return arrow.FixedWidthTypes.Date32
+	case "TIME":
+		return arrow.FixedWidthTypes.Time32ms
+	case "TIMESTAMP":
+		return &arrow.TimestampType{Unit: arrow.Millisecond}
+	}
+	return arrow.BinaryTypes.String
+}
+
+// appendJSONValue appends a value decoded from the JSON results of a job to the builder
+func appendJSONValue(b array.Builder, v interface{}) error {
+	if v == nil {
+		b.AppendNull()
+		return nil
+	}
+	switch b := b.(type) {
+	case *array.StringBuilder:
+		if s, ok := v.(string); ok {
+			b.Append(s)
+			return nil
+		}
+		buf, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		b.Append(string(buf))
+		return nil
+	case *array.BooleanBuilder:
+		if val, ok := v.(bool); ok {
+			b.Append(val)
+			return nil
+		}
+	case *array.Int32Builder:
+		if val, ok := v.(float64); ok {
+			b.Append(int32(val))
+			return nil
+		}
+	case *array.Int64Builder:
+		if val, ok := v.(float64); ok {
+			b.Append(int64(val))
+			return nil
+		}
+	case *array.Float32Builder:
+		if val, ok := v.(float64); ok {
+			b.Append(float32(val))
+			return nil
+		}
+	case *array.Float64Builder:
+		if val, ok := v.(float64); ok {
+			b.Append(val)
+			return nil
+		}
+	}
+	switch val := v.(type) {
+	case string:
+		return b.AppendValueFromString(val)
+	case float64:
+		return b.AppendValueFromString(strconv.FormatFloat(val, 'f', -1, 64))
+	case bool:
+		return b.AppendValueFromString(strconv.FormatBool(val))
+	}
+	return fmt.Errorf("unexpected value %v", v)
+}
@@ -0,0 +1,96 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryArrowREST(t *testing.T) {
+	assert := assert.New(t)
+	var logins int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiv2/login":
+			atomic.AddInt32(&logins, 1)
+			io.WriteString(w, `{"token":"abc"}`)
+		case "/api/v3/sql":
+			io.WriteString(w, `{"id":"1"}`)
+		case "/api/v3/job/1":
+			io.WriteString(w, `{"jobState":"COMPLETED","rowCount":2}`)
+		case "/api/v3/job/1/results":
+			io.WriteString(w, `{"rowCount":2,"schema":[
+				{"name":"id","type":{"name":"BIGINT"}},
+				{"name":"price","type":{"name":"DECIMAL","precision":10,"scale":2}},
+				{"name":"day","type":{"name":"DATE"}},
+				{"name":"tags","type":{"name":"LIST"}}
+			],"rows":[
+				{"id":1,"price":1.5,"day":"2019-01-02","tags":["a"]},
+				{"id":2,"price":null,"day":"2019-01-03","tags":null}
+			]}`)
+		}
+	}))
+	defer srv.Close()
+	db := sql.OpenDB(newTestConnector(t, srv))
+	defer db.Close()
+	reader, err := QueryArrow(context.Background(), db, "SELECT * FROM foo")
+	assert.NoError(err)
+	defer reader.Release()
+	// the reader doesn't hold a pooled connection
+	assert.Equal(0, db.Stats().InUse)
+	assert.Equal(arrow.PrimitiveTypes.Int64, reader.Schema().Field(0).Type)
+	assert.Equal(&arrow.Decimal128Type{Precision: 10, Scale: 2}, reader.Schema().Field(1).Type)
+	assert.True(reader.Next())
+	rec := reader.RecordBatch()
+	assert.Equal(int64(2), rec.NumRows())
+	assert.Equal([]int64{1, 2}, rec.Column(0).(*array.Int64).Int64Values())
+	assert.Equal("1.50", rec.Column(1).(*array.Decimal128).Value(0).ToString(2))
+	assert.True(rec.Column(1).IsNull(1))
+	assert.Equal("2019-01-03", rec.Column(2).(*array.Date32).Value(1).FormattedString())
+	assert.Equal(`["a"]`, rec.Column(3).(*array.String).Value(0))
+	assert.False(reader.Next())
+	assert.NoError(reader.Err())
+
+	// the shared connection is used again, without logging in
+	again, err := QueryArrow(context.Background(), db, "SELECT * FROM foo")
+	assert.NoError(err)
+	again.Release()
+	assert.Equal(int32(2), atomic.LoadInt32(&logins))
+}
+
+func TestQueryArrowFlight(t *testing.T) {
+	assert := assert.New(t)
+	fs := &testFlightServer{
+		schema: arrow.NewSchema([]arrow.Field{
+			{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+			{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+		}, nil),
+	}
+	srv := flight.NewServerWithMiddleware([]flight.ServerMiddleware{flight.CreateServerBasicAuthMiddleware(testFlightAuth{})})
+	assert.NoError(srv.Init("localhost:0"))
+	srv.RegisterFlightService(fs)
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	db, err := sql.Open(DriverName, "grpc://user:pass@"+srv.Addr().String())
+	assert.NoError(err)
+	defer db.Close()
+	reader, err := QueryArrow(context.Background(), db, "SELECT id, name FROM foo")
+	assert.NoError(err)
+	defer reader.Release()
+	var ids []int64
+	for reader.Next() {
+		ids = append(ids, reader.RecordBatch().Column(0).(*array.Int64).Int64Values()...)
+	}
+	assert.NoError(reader.Err())
+	assert.Equal([]int64{0, 1, 2, 3}, ids)
+}
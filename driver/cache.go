@@ -0,0 +1,209 @@
+package driver
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCacheSize = 100
+	defaultCacheRows = 100000
+)
+
+// ResultCache is a size bounded cache of the results of queries using the REST API.
+// Results are keyed by the coordinator, the user, the final SQL, the context path and
+// the args of the query, so a cache can be shared by connectors with different credentials,
+// and are only cached once they have been read until the end. A hit skips submitting the
+// job, polling it and fetching its pages. Use WithoutCache to skip the cache for a query
+type ResultCache struct {
+	mu      sync.Mutex
+	size    int
+	maxRows int
+	rows    int
+	ttl     time.Duration
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key     string
+	expires time.Time
+	columns columns
+	schema  []schema
+	rows    []map[string]interface{}
+}
+
+// NewResultCache returns a cache holding the results of up to size queries, with up to maxRows
+// rows between them, for the ttl. a result with more rows than maxRows isn't cached
+func NewResultCache(size int, maxRows int, ttl time.Duration) *ResultCache {
+	if size <= 0 {
+		size = defaultCacheSize
+	}
+	if maxRows <= 0 {
+		maxRows = defaultCacheRows
+	}
+	return &ResultCache{
+		size:    size,
+		maxRows: maxRows,
+		ttl:     ttl,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Len returns the number of cached results, including expired ones which haven't been evicted yet
+func (c *ResultCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Purge removes all the cached results
+func (c *ResultCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Init()
+	c.entries = make(map[string]*list.Element)
+	c.rows = 0
+}
+
+func (c *ResultCache) get(key string) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(el)
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	return entry
+}
+
+func (c *ResultCache) put(entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(entry.rows) > c.maxRows {
+		return
+	}
+	entry.expires = time.Now().Add(c.ttl)
+	if el, ok := c.entries[entry.key]; ok {
+		c.remove(el)
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	c.rows += len(entry.rows)
+	for c.lru.Len() > c.size || c.rows > c.maxRows {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *ResultCache) remove(el *list.Element) {
+	entry := el.Value.(*cacheEntry)
+	c.lru.Remove(el)
+	delete(c.entries, entry.key)
+	c.rows -= len(entry.rows)
+}
+
+// holds returns true if a result with the number of rows can be cached
+func (c *ResultCache) holds(rows int) bool {
+	return rows <= c.maxRows
+}
+
+// cacheKey returns the key of a query, buf is the request with the final SQL and the context path.
+// the results a user may read depend on their permissions, so the key starts with the coordinator
+// and the user, or a hash of the personal access token
+func (c *connection) cacheKey(buf []byte, q query) string {
+	key := c.endpoint() + "\x00" + c.project + "\x00" + c.username
+	if c.bearer {
+		sum := sha256.Sum256([]byte(c.token))
+		key += "\x00" + hex.EncodeToString(sum[:])
+	}
+	key += "\x00" + string(buf)
+	for _, arg := range q.args {
+		key += fmt.Sprintf("\x00%T:%v", arg.Value, arg.Value)
+	}
+	return key
+}
+
+type noCacheKey struct{}
+
+// WithoutCache returns a context which makes queries skip the result cache
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+func cacheEnabled(ctx context.Context) bool {
+	skip, _ := ctx.Value(noCacheKey{}).(bool)
+	return !skip
+}
+
+// cached returns the rows of a cache hit
+func (q query) cached(ctx context.Context, c *connection, entry *cacheEntry) *rows {
+	info := &QueryInfo{
+		SQL:      q.Query,
+		Args:     q.args,
+		Context:  q.Context,
+		Endpoint: c.endpoint(),
+		Started:  time.Now(),
+	}
+	ctx = c.observer.QuerySubmitted(ctx, info)
+	res := &result{
+		ctx:     ctx,
+		conn:    c,
+		info:    info,
+		columns: entry.columns,
+		schema:  entry.schema,
+		offset:  len(entry.rows),
+		total:   len(entry.rows),
+	}
+	// the cached rows are shared by every hit, so each gets a copy it can't change them through
+	res.rows = &rows{parent: res, rows: copyValue(entry.rows).([]map[string]interface{})}
+	return res.rows
+}
+
+// record keeps a page of the result to cache it once it's read until the end, giving up
+// on a result with more rows than the cache holds
+func (r *result) record(page []map[string]interface{}) {
+	if r.cache == nil {
+		return
+	}
+	if !r.cache.holds(len(r.pages) + len(page)) {
+		r.cache = nil
+		r.pages = nil
+		return
+	}
+	r.pages = append(r.pages, page...)
+}
+
+// copyValue returns a deep copy of a value decoded from JSON
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []map[string]interface{}:
+		c := make([]map[string]interface{}, len(v))
+		for i, m := range v {
+			c[i] = copyValue(m).(map[string]interface{})
+		}
+		return c
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			c[k] = copyValue(val)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, val := range v {
+			c[i] = copyValue(val)
+		}
+		return c
+	}
+	return v
+}
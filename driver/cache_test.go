@@ -0,0 +1,127 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultCache(t *testing.T) {
+	assert := assert.New(t)
+	var submits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiv2/login":
+			io.WriteString(w, `{"token":"abc"}`)
+		case "/api/v3/sql":
+			atomic.AddInt32(&submits, 1)
+			io.WriteString(w, `{"id":"1"}`)
+		case "/api/v3/job/1":
+			io.WriteString(w, `{"jobState":"COMPLETED","rowCount":3}`)
+		case "/api/v3/job/1/results":
+			if r.URL.Query().Get("offset") == "2" {
+				io.WriteString(w, `{"rowCount":3,"schema":[{"name":"a"}],"rows":[{"a":3}]}`)
+				return
+			}
+			io.WriteString(w, `{"rowCount":3,"schema":[{"name":"a"}],"rows":[{"a":1},{"a":2}]}`)
+		}
+	}))
+	defer srv.Close()
+	c := newTestConnector(t, srv)
+	c.cfg.PageSize = 2
+	c.cfg.Cache = NewResultCache(10, 0, time.Minute)
+	db := sql.OpenDB(c)
+	defer db.Close()
+	read := func(ctx context.Context, args ...interface{}) []int {
+		rows, err := db.QueryContext(ctx, "SELECT a FROM foo WHERE a > ?", args...)
+		assert.NoError(err)
+		defer rows.Close()
+		var vals []int
+		for rows.Next() {
+			var a int
+			assert.NoError(rows.Scan(&a))
+			vals = append(vals, a)
+		}
+		assert.NoError(rows.Err())
+		return vals
+	}
+	ctx := context.Background()
+	assert.Equal([]int{1, 2, 3}, read(ctx, 0))
+	assert.Equal([]int{1, 2, 3}, read(ctx, 0))
+	assert.Equal(int32(1), atomic.LoadInt32(&submits))
+	assert.Equal([]int{1, 2, 3}, read(WithoutCache(ctx), 0))
+	assert.Equal(int32(2), atomic.LoadInt32(&submits))
+	// different args are a different query
+	assert.Equal([]int{1, 2, 3}, read(ctx, 1))
+	assert.Equal(int32(3), atomic.LoadInt32(&submits))
+	assert.Equal(2, c.cfg.Cache.Len())
+	c.cfg.Cache.Purge()
+	assert.Equal([]int{1, 2, 3}, read(ctx, 0))
+	assert.Equal(int32(4), atomic.LoadInt32(&submits))
+}
+
+func TestResultCacheEviction(t *testing.T) {
+	assert := assert.New(t)
+	cache := NewResultCache(2, 0, time.Minute)
+	cache.put(&cacheEntry{key: "a"})
+	cache.put(&cacheEntry{key: "b"})
+	assert.NotNil(cache.get("a"))
+	cache.put(&cacheEntry{key: "c"})
+	assert.Nil(cache.get("b"))
+	assert.NotNil(cache.get("a"))
+	assert.NotNil(cache.get("c"))
+
+	cache = NewResultCache(2, 0, -time.Second)
+	cache.put(&cacheEntry{key: "a"})
+	assert.Nil(cache.get("a"))
+	assert.Equal(0, cache.Len())
+}
+
+func TestResultCacheRows(t *testing.T) {
+	assert := assert.New(t)
+	row := map[string]interface{}{"a": 1.0}
+	cache := NewResultCache(10, 3, time.Minute)
+	cache.put(&cacheEntry{key: "a", rows: []map[string]interface{}{row, row}})
+	cache.put(&cacheEntry{key: "b", rows: []map[string]interface{}{row}})
+	// too large to be cached
+	cache.put(&cacheEntry{key: "c", rows: []map[string]interface{}{row, row, row, row}})
+	assert.Nil(cache.get("c"))
+	assert.Equal(2, cache.Len())
+	// evicts a to make room
+	cache.put(&cacheEntry{key: "d", rows: []map[string]interface{}{row, row}})
+	assert.Nil(cache.get("a"))
+	assert.NotNil(cache.get("b"))
+	assert.NotNil(cache.get("d"))
+}
+
+func TestResultCacheKey(t *testing.T) {
+	assert := assert.New(t)
+	buf := []byte(`{"sql":"SELECT 1"}`)
+	alice := &connection{hostname: "localhost", port: 9047, username: "alice"}
+	bob := &connection{hostname: "localhost", port: 9047, username: "bob"}
+	other := &connection{hostname: "other", port: 9047, username: "alice"}
+	pat := &connection{hostname: "localhost", port: 9047, token: "t1", bearer: true}
+	pat2 := &connection{hostname: "localhost", port: 9047, token: "t2", bearer: true}
+	assert.Equal(alice.cacheKey(buf, query{}), alice.cacheKey(buf, query{}))
+	assert.NotEqual(alice.cacheKey(buf, query{}), bob.cacheKey(buf, query{}))
+	assert.NotEqual(alice.cacheKey(buf, query{}), other.cacheKey(buf, query{}))
+	assert.NotEqual(pat.cacheKey(buf, query{}), pat2.cacheKey(buf, query{}))
+	assert.NotContains(pat.cacheKey(buf, query{}), "t1")
+}
+
+func TestResultCacheCopiesRows(t *testing.T) {
+	assert := assert.New(t)
+	c := &connection{hostname: "localhost", port: 9047, observer: NopObserver{}}
+	entry := &cacheEntry{rows: []map[string]interface{}{{"a": []interface{}{1.0}}}}
+	r := query{}.cached(context.Background(), c, entry)
+	r.rows[0]["a"].([]interface{})[0] = 2.0
+	r.rows[0]["b"] = 3.0
+	assert.Equal([]map[string]interface{}{{"a": []interface{}{1.0}}}, entry.rows)
+}
@@ -0,0 +1,318 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// The types of catalog entities
+const (
+	EntitySpace   = "space"
+	EntitySource  = "source"
+	EntityFolder  = "folder"
+	EntityDataset = "dataset"
+	EntityFile    = "file"
+	EntityHome    = "home"
+)
+
+// The types of datasets
+const (
+	DatasetVirtual  = "VIRTUAL_DATASET"
+	DatasetPhysical = "PHYSICAL_DATASET"
+)
+
+// The types of the items listed in the catalog
+const (
+	ItemContainer = "CONTAINER"
+	ItemDataset   = "DATASET"
+	ItemFile      = "FILE"
+)
+
+// ErrCatalogConflict is returned when an entity is updated or deleted with a tag which isn't
+// its current one, because it was changed since it was read
+var ErrCatalogConflict = errors.New("catalog entity was changed since it was read")
+
+// ErrCatalogExists is returned when an entity is created, or a dataset promoted, at a path
+// which already has one
+var ErrCatalogExists = errors.New("catalog entity already exists")
+
+// SkipContainer is returned by a WalkFunc to skip the children of a container
+var SkipContainer = errors.New("skip this container")
+
+// CatalogItem is an item listed in the catalog, or a child of a container
+type CatalogItem struct {
+	ID   string   `json:"id"`
+	Path []string `json:"path"`
+	Tag  string   `json:"tag,omitempty"`
+	// Type is one of the Item constants
+	Type string `json:"type"`
+	// ContainerType is the type of a container, such as SPACE, SOURCE, FOLDER or HOME
+	ContainerType string `json:"containerType,omitempty"`
+	// DatasetType is the type of a dataset, such as VIRTUAL, PROMOTED or DIRECT
+	DatasetType string `json:"datasetType,omitempty"`
+}
+
+// CatalogEntity is a space, source, folder, dataset, file or home of the catalog
+type CatalogEntity struct {
+	// EntityType is one of the Entity constants
+	EntityType string   `json:"entityType"`
+	ID         string   `json:"id,omitempty"`
+	Path       []string `json:"path,omitempty"`
+	// Name is the name of a space or source
+	Name string `json:"name,omitempty"`
+	// Tag is the version of the entity, which must be sent back to update or delete it
+	Tag string `json:"tag,omitempty"`
+	// Type is the type of a dataset, one of the Dataset constants, or of a source, such as S3
+	Type string `json:"type,omitempty"`
+	// SQL is the query of a view
+	SQL string `json:"sql,omitempty"`
+	// SQLContext is the path the query of a view runs in
+	SQLContext []string `json:"sqlContext,omitempty"`
+	// Format is the format of a physical dataset
+	Format *Format `json:"format,omitempty"`
+	// Fields are the fields of a dataset
+	Fields []Field `json:"fields,omitempty"`
+	// Config is the configuration of a source
+	Config map[string]interface{} `json:"config,omitempty"`
+	// Children are the children of a container
+	Children []CatalogItem `json:"children,omitempty"`
+}
+
+// Format is the format of the files of a physical dataset
+type Format struct {
+	// Type is the type of the files, such as Parquet, JSON, Text, Excel, Delta or Iceberg
+	Type string `json:"type"`
+	// the options of Text files
+	FieldDelimiter          string `json:"fieldDelimiter,omitempty"`
+	LineDelimiter           string `json:"lineDelimiter,omitempty"`
+	Quote                   string `json:"quote,omitempty"`
+	Comment                 string `json:"comment,omitempty"`
+	Escape                  string `json:"escape,omitempty"`
+	SkipFirstLine           bool   `json:"skipFirstLine,omitempty"`
+	ExtractHeader           bool   `json:"extractHeader,omitempty"`
+	TrimHeader              bool   `json:"trimHeader,omitempty"`
+	AutoGenerateColumnNames bool   `json:"autoGenerateColumnNames,omitempty"`
+	// the options of Excel files
+	SheetName      string `json:"sheetName,omitempty"`
+	HasMergedCells bool   `json:"hasMergedCells,omitempty"`
+}
+
+// Field is a field of a dataset
+type Field struct {
+	Name string    `json:"name"`
+	Type FieldType `json:"type"`
+}
+
+// FieldType is the type of a field
+type FieldType struct {
+	Name      string  `json:"name"`
+	Precision int     `json:"precision,omitempty"`
+	Scale     int     `json:"scale,omitempty"`
+	SubSchema []Field `json:"subSchema,omitempty"`
+}
+
+// Catalog is a client for the catalog REST API, sharing the configuration and authentication of the connector
+type Catalog struct {
+	conn *connection
+}
+
+// WalkFunc is called by Walk for each item of the catalog. Returning SkipContainer skips the children
+// of a container, any other error stops the walk and is returned by Walk
+type WalkFunc func(item CatalogItem) error
+
+// Catalog returns a client for the catalog. Only the REST API has the catalog, so it returns an error
+// for the grpc schemes
+func (c *Connector) Catalog(ctx context.Context) (*Catalog, error) {
+	conn, err := c.restConnection(ctx, "catalogs")
+	if err != nil {
+		return nil, err
+	}
+	return &Catalog{conn: conn}, nil
+}
+
+func (c *connection) getCatalogURL(id string) string {
+	if id == "" {
+		return c.getAPIURL("/catalog")
+	}
+	return c.getAPIURL("/catalog/" + url.PathEscape(id))
+}
+
+func (c *connection) getCatalogPathURL(path []string) string {
+	parts := make([]string, len(path))
+	for i, part := range path {
+		parts[i] = url.PathEscape(part)
+	}
+	return c.getAPIURL("/catalog/by-path/" + strings.Join(parts, "/"))
+}
+
+// conflictError returns the error of a request, wrapping the conflict error for a 409
+func conflictError(err error, conflict error) error {
+	if serr, ok := err.(*StatusError); ok && serr.StatusCode == http.StatusConflict {
+		return fmt.Errorf("%w: %w", conflict, err)
+	}
+	return err
+}
+
+// List returns the top level items of the catalog, its spaces, sources and homes
+func (c *Catalog) List(ctx context.Context) ([]CatalogItem, error) {
+	var res struct {
+		Data []CatalogItem `json:"data"`
+	}
+	if err := c.conn.doJSON(ctx, http.MethodGet, c.conn.getCatalogURL(""), nil, &res); err != nil {
+		return nil, err
+	}
+	return res.Data, nil
+}
+
+// Get returns the entity with the id
+func (c *Catalog) Get(ctx context.Context, id string) (*CatalogEntity, error) {
+	if id == "" {
+		return nil, fmt.Errorf("missing catalog id")
+	}
+	var entity CatalogEntity
+	if err := c.conn.doJSON(ctx, http.MethodGet, c.conn.getCatalogURL(id), nil, &entity); err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+// GetByPath returns the entity at the path, such as "sales", "2019", "orders"
+func (c *Catalog) GetByPath(ctx context.Context, path ...string) (*CatalogEntity, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("missing catalog path")
+	}
+	var entity CatalogEntity
+	if err := c.conn.doJSON(ctx, http.MethodGet, c.conn.getCatalogPathURL(path), nil, &entity); err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+// Walk calls fn for each item under the path, or the whole catalog for an empty path, descending
+// into the containers depth first
+func (c *Catalog) Walk(ctx context.Context, path []string, fn WalkFunc) error {
+	var items []CatalogItem
+	if len(path) == 0 {
+		var err error
+		if items, err = c.List(ctx); err != nil {
+			return err
+		}
+	} else {
+		entity, err := c.GetByPath(ctx, path...)
+		if err != nil {
+			return err
+		}
+		items = entity.Children
+	}
+	return c.walk(ctx, items, fn)
+}
+
+func (c *Catalog) walk(ctx context.Context, items []CatalogItem, fn WalkFunc) error {
+	for _, item := range items {
+		if err := fn(item); err != nil {
+			if err == SkipContainer {
+				continue
+			}
+			return err
+		}
+		if item.Type != ItemContainer {
+			continue
+		}
+		entity, err := c.Get(ctx, item.ID)
+		if err != nil {
+			return err
+		}
+		if err := c.walk(ctx, entity.Children, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create creates the entity, such as a space, folder or view, and returns it as created. An error
+// wrapping ErrCatalogExists is returned if there is already an entity at its path. The request isn't
+// sent again if its response is lost, so check whether the entity exists before creating it again
+func (c *Catalog) Create(ctx context.Context, entity *CatalogEntity) (*CatalogEntity, error) {
+	var created CatalogEntity
+	if err := c.conn.doJSON(ctx, http.MethodPost, c.conn.getCatalogURL(""), entity, &created); err != nil {
+		return nil, conflictError(err, ErrCatalogExists)
+	}
+	c.conn.log.Info("created catalog entity", "type", entity.EntityType, "id", created.ID)
+	return &created, nil
+}
+
+// CreateSpace creates a space
+func (c *Catalog) CreateSpace(ctx context.Context, name string) (*CatalogEntity, error) {
+	return c.Create(ctx, &CatalogEntity{EntityType: EntitySpace, Name: name})
+}
+
+// CreateFolder creates a folder at the path, in a space or another folder
+func (c *Catalog) CreateFolder(ctx context.Context, path ...string) (*CatalogEntity, error) {
+	return c.Create(ctx, &CatalogEntity{EntityType: EntityFolder, Path: path})
+}
+
+// CreateView creates a view at the path with the query, which runs in the context path if not empty
+func (c *Catalog) CreateView(ctx context.Context, path []string, sql string, sqlContext []string) (*CatalogEntity, error) {
+	return c.Create(ctx, &CatalogEntity{EntityType: EntityDataset, Type: DatasetVirtual, Path: path, SQL: sql, SQLContext: sqlContext})
+}
+
+// Update replaces the entity, such as the query of a view, and returns it with its new tag. The tag of
+// the entity must be the current one, else an error wrapping ErrCatalogConflict is returned and the
+// entity should be read again before retrying
+func (c *Catalog) Update(ctx context.Context, entity *CatalogEntity) (*CatalogEntity, error) {
+	if entity.ID == "" {
+		return nil, fmt.Errorf("missing catalog id")
+	}
+	var updated CatalogEntity
+	if err := c.conn.doJSON(ctx, http.MethodPut, c.conn.getCatalogURL(entity.ID), entity, &updated); err != nil {
+		return nil, conflictError(err, ErrCatalogConflict)
+	}
+	c.conn.log.Info("updated catalog entity", "type", entity.EntityType, "id", entity.ID)
+	return &updated, nil
+}
+
+// Delete deletes the entity with the id. If the tag isn't empty and isn't the current one of the entity,
+// an error wrapping ErrCatalogConflict is returned
+func (c *Catalog) Delete(ctx context.Context, id string, tag string) error {
+	if id == "" {
+		return fmt.Errorf("missing catalog id")
+	}
+	u := c.conn.getCatalogURL(id)
+	if tag != "" {
+		u += "?tag=" + url.QueryEscape(tag)
+	}
+	if err := c.conn.doJSON(ctx, http.MethodDelete, u, nil, nil); err != nil {
+		if tag == "" {
+			return err
+		}
+		return conflictError(err, ErrCatalogConflict)
+	}
+	c.conn.log.Info("deleted catalog entity", "id", id)
+	return nil
+}
+
+// Promote promotes the file or folder at the path of a source to a physical dataset with the format.
+// An error wrapping ErrCatalogExists is returned if it's already a dataset
+func (c *Catalog) Promote(ctx context.Context, path []string, format Format) (*CatalogEntity, error) {
+	entity, err := c.GetByPath(ctx, path...)
+	if err != nil {
+		return nil, err
+	}
+	dataset := &CatalogEntity{
+		EntityType: EntityDataset,
+		ID:         entity.ID,
+		Path:       entity.Path,
+		Type:       DatasetPhysical,
+		Format:     &format,
+	}
+	var promoted CatalogEntity
+	if err := c.conn.doJSON(ctx, http.MethodPost, c.conn.getCatalogURL(entity.ID), dataset, &promoted); err != nil {
+		return nil, conflictError(err, ErrCatalogExists)
+	}
+	c.conn.log.Info("promoted dataset", "id", promoted.ID, "format", format.Type)
+	return &promoted, nil
+}
@@ -0,0 +1,142 @@
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalog(t *testing.T) {
+	assert := assert.New(t)
+	var created, updated, promoted CatalogEntity
+	var deleted string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.EscapedPath() {
+		case "POST /apiv2/login":
+			io.WriteString(w, `{"token":"abc"}`)
+		case "GET /api/v3/catalog":
+			io.WriteString(w, `{"data":[
+				{"id":"s1","path":["sales"],"type":"CONTAINER","containerType":"SPACE"},
+				{"id":"src","path":["lake"],"type":"CONTAINER","containerType":"SOURCE"}
+			]}`)
+		case "GET /api/v3/catalog/s1":
+			io.WriteString(w, `{"entityType":"space","id":"s1","name":"sales","tag":"t1","children":[
+				{"id":"f1","path":["sales","2019"],"type":"CONTAINER","containerType":"FOLDER"},
+				{"id":"v1","path":["sales","totals"],"type":"DATASET","datasetType":"VIRTUAL"}
+			]}`)
+		case "GET /api/v3/catalog/f1":
+			io.WriteString(w, `{"entityType":"folder","id":"f1","path":["sales","2019"],"children":[
+				{"id":"v2","path":["sales","2019","orders"],"type":"DATASET","datasetType":"VIRTUAL"}
+			]}`)
+		case "GET /api/v3/catalog/by-path/sales/2019/orders":
+			io.WriteString(w, `{"entityType":"dataset","id":"v2","type":"VIRTUAL_DATASET","path":["sales","2019","orders"],"tag":"t2","sql":"SELECT 1","fields":[{"name":"a","type":{"name":"INTEGER"}}]}`)
+		case "GET /api/v3/catalog/by-path/lake/orders%2F2019.csv":
+			io.WriteString(w, `{"entityType":"file","id":"dremio:/lake/orders/2019.csv","path":["lake","orders/2019.csv"]}`)
+		case "POST /api/v3/catalog":
+			json.NewDecoder(r.Body).Decode(&created)
+			created.ID, created.Tag = "new", "t1"
+			json.NewEncoder(w).Encode(created)
+		case "PUT /api/v3/catalog/v2":
+			json.NewDecoder(r.Body).Decode(&updated)
+			if updated.Tag != "t2" {
+				w.WriteHeader(http.StatusConflict)
+				io.WriteString(w, `{"errorMessage":"tag mismatch"}`)
+				return
+			}
+			updated.Tag = "t3"
+			json.NewEncoder(w).Encode(updated)
+		case "DELETE /api/v3/catalog/v2":
+			deleted = r.URL.Query().Get("tag")
+		case "POST /api/v3/catalog/dremio:%2Flake%2Forders%2F2019.csv":
+			json.NewDecoder(r.Body).Decode(&promoted)
+			json.NewEncoder(w).Encode(promoted)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	catalog, err := newTestConnector(t, srv).Catalog(ctx)
+	assert.NoError(err)
+
+	var paths []string
+	assert.NoError(catalog.Walk(ctx, nil, func(item CatalogItem) error {
+		paths = append(paths, strings.Join(item.Path, "."))
+		if item.ContainerType == "SOURCE" {
+			return SkipContainer
+		}
+		return nil
+	}))
+	assert.Equal([]string{"sales", "sales.2019", "sales.2019.orders", "sales.totals", "lake"}, paths)
+
+	view, err := catalog.GetByPath(ctx, "sales", "2019", "orders")
+	assert.NoError(err)
+	assert.Equal("SELECT 1", view.SQL)
+	assert.Equal("INTEGER", view.Fields[0].Type.Name)
+
+	space, err := catalog.CreateSpace(ctx, "marketing")
+	assert.NoError(err)
+	assert.Equal("new", space.ID)
+	assert.Equal(EntitySpace, created.EntityType)
+	_, err = catalog.CreateView(ctx, []string{"marketing", "leads"}, "SELECT * FROM leads", []string{"lake"})
+	assert.NoError(err)
+	assert.Equal(DatasetVirtual, created.Type)
+	assert.Equal([]string{"lake"}, created.SQLContext)
+
+	view.SQL = "SELECT 2"
+	view, err = catalog.Update(ctx, view)
+	assert.NoError(err)
+	assert.Equal("t3", view.Tag)
+	assert.Equal("SELECT 2", updated.SQL)
+	_, err = catalog.Update(ctx, view)
+	assert.True(errors.Is(err, ErrCatalogConflict))
+
+	assert.NoError(catalog.Delete(ctx, "v2", "t3"))
+	assert.Equal("t3", deleted)
+
+	dataset, err := catalog.Promote(ctx, []string{"lake", "orders/2019.csv"}, Format{Type: "Text", FieldDelimiter: ",", ExtractHeader: true})
+	assert.NoError(err)
+	assert.Equal(DatasetPhysical, dataset.Type)
+	assert.Equal("dremio:/lake/orders/2019.csv", dataset.ID)
+	assert.Equal(",", promoted.Format.FieldDelimiter)
+}
+
+func TestCatalogCreateIsNotRetried(t *testing.T) {
+	assert := assert.New(t)
+	var posts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /apiv2/login":
+			io.WriteString(w, `{"token":"abc"}`)
+		case "POST /api/v3/catalog":
+			if atomic.AddInt32(&posts, 1) == 1 {
+				// the space may have been created anyway
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusConflict)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	catalog, err := newTestConnector(t, srv).Catalog(ctx)
+	assert.NoError(err)
+	_, err = catalog.CreateSpace(ctx, "marketing")
+	var serr *StatusError
+	assert.True(errors.As(err, &serr))
+	assert.Equal(http.StatusBadGateway, serr.StatusCode)
+	assert.Equal(int32(1), atomic.LoadInt32(&posts))
+	_, err = catalog.CreateSpace(ctx, "marketing")
+	assert.True(errors.Is(err, ErrCatalogExists))
+	assert.False(errors.Is(err, ErrCatalogConflict))
+}
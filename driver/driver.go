@@ -8,12 +8,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"regexp"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/pinpt/go-dremio/dremiosql"
 )
 
 // ErrTransactionNotSupported is returned if transactions are attempted to be used
@@ -27,14 +31,36 @@ type db struct {
 
 // make sure our db implements the full driver interface
 var _ driver.Driver = (*db)(nil)
+var _ driver.DriverContext = (*db)(nil)
 
 type connection struct {
 	proto    string
 	hostname string
 	port     int
 	token    string
+	tokenMu  sync.Mutex // guards token, the connection of the REST clients is used concurrently
 	pagesize int
 	context  []string
+	refs     map[string]Ref
+	username string
+	password string
+	retry    RetryPolicy
+	client   *http.Client
+	project  string
+	engine   string
+	bearer   bool
+
+	schemaChange    RetryPolicy
+	onSchemaChange  SchemaChangeFunc
+	continueOnError bool
+	log             Logger
+	observer        Observer
+	cache           *ResultCache
+	queries         *queryGroup
+	jobs            *jobLimiter
+	flight          *flightClient
+	// connector is the connector which opened the connection
+	connector *Connector
 }
 
 // make sure our connection implements the full driver.Conn interfaces
@@ -60,10 +86,12 @@ var _ driver.StmtExecContext = (*statement)(nil)
 var _ driver.StmtQueryContext = (*statement)(nil)
 
 type query struct {
-	Query   string   `json:"sql"`
-	Context []string `json:"context,omitempty"`
+	Query      string         `json:"sql"`
+	Context    []string       `json:"context,omitempty"`
+	References map[string]Ref `json:"references,omitempty"`
+	Engine     string         `json:"engineName,omitempty"`
 
-	buf []byte
+	args []driver.NamedValue
 }
 
 var paramRe = regexp.MustCompile("(\\?)")
@@ -79,7 +107,7 @@ func replacePlaceholders(q string, v valuer) string {
 			var res string
 			switch v := val.(type) {
 			case string:
-				res = fmt.Sprintf(` '%s' `, v)
+				res = " " + dremiosql.QuoteLiteral(v) + " "
 			default:
 				res = fmt.Sprintf(" %v ", val)
 			}
@@ -92,7 +120,8 @@ func replacePlaceholders(q string, v valuer) string {
 	})
 }
 
-func (q query) buildNamed(args []driver.NamedValue) ([]byte, error) {
+// bind returns the query with the placeholders replaced by the args
+func (q query) bind(args []driver.NamedValue) query {
 	if len(args) > 0 {
 		q.Query = replacePlaceholders(q.Query, func(index int) driver.Value {
 			if index < len(args) {
@@ -101,59 +130,152 @@ func (q query) buildNamed(args []driver.NamedValue) ([]byte, error) {
 			return nil
 		}) + " " // dremio has an issue where you need to have a space at end of your query (from forums)
 	}
-	return json.Marshal(q)
+	return q
 }
 
-func (q query) build(args []driver.Value) ([]byte, error) {
-	if len(args) > 0 {
-		q.Query = replacePlaceholders(q.Query, func(index int) driver.Value {
-			if index < len(args) {
-				return args[index]
-			}
-			return nil
-		}) + " " // dremio has an issue where you need to have a space at end of your query (from forums)
-	}
-	return json.Marshal(q)
+func (q query) buildNamed(args []driver.NamedValue) ([]byte, error) {
+	return json.Marshal(q.bind(args))
 }
 
-func (q query) send(c *connection, buf []byte) (driver.Rows, error) {
-	resp, err := c.post(c.getQueryURL(), buf)
+func (q query) submit(ctx context.Context, c *connection, buf []byte) (*jobid, error) {
+	// submitting the job again can run the query twice, so it's only done if the policy allows it
+	resp, err := c.send(ctx, http.MethodPost, c.getQueryURL(), buf, c.retry.Resubmit)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		if serr, ok := err.(*StatusError); ok {
+			switch serr.StatusCode {
+			case http.StatusUnauthorized, http.StatusNotFound:
+				// a new connection will login again
+				return nil, fmt.Errorf("%w: %w", driver.ErrBadConn, err)
+			}
+			return nil, err
+		}
+		if unsent(err) || c.retry.Resubmit {
+			// the coordinator couldn't be reached even after retrying, or running the query
+			// again is allowed, so database/sql can retry it on a new connection
+			return nil, fmt.Errorf("%w: %w", driver.ErrBadConn, err)
+		}
+		// the job may have been created before the connection failed
 		return nil, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		switch resp.StatusCode {
-		case http.StatusBadGateway:
-		case http.StatusGone:
-		case http.StatusNotFound:
-			return nil, sql.ErrConnDone
-		}
-		buf, _ := ioutil.ReadAll(resp.Body)
-		return nil, errors.New(string(buf))
-	}
 	var job jobid
 	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
 		return nil, fmt.Errorf("query error. status code: %v", resp.StatusCode)
 	}
-	q.buf = buf
-	return newResult(c, job.ID, &q)
+	return &job, nil
 }
 
-func (q query) query(ctx context.Context, c *connection, args []driver.Value) (driver.Rows, error) {
-	r, err := q.build(args)
+func (q query) send(ctx context.Context, c *connection, buf []byte) (driver.Rows, error) {
+	info := &QueryInfo{
+		SQL:      q.Query,
+		Args:     q.args,
+		Context:  q.Context,
+		Endpoint: c.endpoint(),
+		Started:  time.Now(),
+	}
+	ctx = c.observer.QuerySubmitted(ctx, info)
+	rows, jobid, err := q.run(ctx, c, buf, info)
 	if err != nil {
-		return nil, err
+		c.observer.QueryFailed(ctx, info, QueryFailedEvent{JobID: jobid, Err: err, Duration: time.Since(info.Started)})
 	}
-	return q.send(c, r)
+	return rows, err
+}
+
+func (q query) run(ctx context.Context, c *connection, buf []byte, info *QueryInfo) (driver.Rows, string, error) {
+	var attempt, schemaChanges int
+	var jobid string
+	for submissions := 1; ; submissions++ {
+		release, err := c.acquireJob(ctx, info)
+		if err != nil {
+			return nil, jobid, err
+		}
+		started := time.Now()
+		job, err := q.submit(ctx, c, buf)
+		if err != nil {
+			release()
+			return nil, jobid, err
+		}
+		jobid = job.ID
+		c.observer.JobCreated(ctx, info, JobCreatedEvent{JobID: jobid, Attempt: submissions, Duration: time.Since(started)})
+		rows, err := newResult(ctx, c, jobid, info)
+		release()
+		jerr, ok := err.(*JobError)
+		if !ok {
+			return rows, jobid, err
+		}
+		var wait time.Duration
+		if jerr.Category == "SCHEMA_CHANGE" {
+			// this is a failure that needs to be restarted because the schema is in learning mode
+			schemaChanges++
+			if schemaChanges >= c.schemaChange.attempts() {
+				return nil, jobid, &SchemaChangeError{Attempts: schemaChanges, Err: jerr}
+			}
+			if c.onSchemaChange != nil {
+				c.onSchemaChange(ctx, jerr.JobID, schemaChanges, jerr)
+			}
+			wait = c.schemaChange.backoff(schemaChanges)
+			c.log.Info("resubmitting query after schema change", "job", jerr.JobID, "attempt", schemaChanges, "backoff", wait)
+			c.retried(ctx, RetrySchemaChange, schemaChanges, jerr)
+		} else {
+			attempt++
+			if !c.retry.retryableJob(jerr) || attempt >= c.retry.attempts() {
+				return nil, jobid, err
+			}
+			// the job failed for a transient reason, submit it again
+			wait = c.retry.backoff(attempt)
+			c.log.Warn("resubmitting failed query", "job", jerr.JobID, "category", jerr.Category, "attempt", attempt, "backoff", wait)
+			c.retried(ctx, RetryJob, attempt, jerr)
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, jobid, err
+		}
+	}
+}
+
+func (q query) query(ctx context.Context, c *connection, args []driver.Value) (driver.Rows, error) {
+	return q.queryNamed(ctx, c, valueToNamedValue(args))
 }
 
 func (q query) queryNamed(ctx context.Context, c *connection, args []driver.NamedValue) (driver.Rows, error) {
+	if err := checkOptions(ctx); err != nil {
+		return nil, err
+	}
+	if len(splitStatements(q.Query)) > 1 {
+		return newScriptRows(ctx, c, q, args)
+	}
+	return q.execute(ctx, c, args)
+}
+
+// execute runs a single statement
+func (q query) execute(ctx context.Context, c *connection, args []driver.NamedValue) (driver.Rows, error) {
+	if c.flight != nil {
+		return c.flight.query(ctx, c, q)
+	}
 	r, err := q.buildNamed(args)
 	if err != nil {
 		return nil, err
 	}
-	return q.send(c, r)
+	if c.cache == nil || !cacheEnabled(ctx) {
+		return q.share(ctx, c, r)
+	}
+	key := c.cacheKey(r, q)
+	if entry := c.cache.get(key); entry != nil {
+		c.log.Debug("using cached result", "sql", q.Query)
+		return q.cached(ctx, c, entry), nil
+	}
+	dr, err := q.share(ctx, c, r)
+	if err != nil {
+		return nil, err
+	}
+	// record the pages as they are read, the result is cached once the last one has been fetched
+	res := dr.(*rows).parent
+	res.cache = c.cache
+	res.key = key
+	res.record(res.rows.rows)
+	return dr, nil
 }
 
 const defaultPort = 443
@@ -169,114 +291,213 @@ const defaultPageSize = 500
 // The returned connection is only used by one goroutine at a
 // time.
 func (d *db) Open(name string) (driver.Conn, error) {
-	u, err := url.Parse(name)
+	c, err := d.OpenConnector(name)
 	if err != nil {
 		return nil, err
 	}
+	return c.Connect(context.Background())
+}
 
-	port := defaultPort
-
-	if u.Port() != "" {
-		port, err = strconv.Atoi(u.Port())
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	if u.User == nil {
-		return nil, fmt.Errorf("missing username and password")
-	}
-
-	pagesize := defaultPageSize
-	ps := u.Query().Get("pagesize")
-	if ps != "" {
-		pagesize, err = strconv.Atoi(ps)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing pagesize. %v", err)
-		}
-		if pagesize <= 0 || pagesize > 500 {
-			return nil, fmt.Errorf("invalid page size. must be between 1-500")
-		}
-	}
-
-	var context []string
-	c := u.Query().Get("context")
-	if c != "" {
-		// try and turn something like Samples."samples.dremio.com" -> [Samples, samples.dremio.com]
-		re := regexp.MustCompile(`("[\w+\.\s]+")|([\w]+)`)
-		toks := re.FindAllString(c, -1)
-		for _, tok := range toks {
-			context = append(context, strings.Replace(tok, `"`, "", -1))
-		}
-	}
-
-	conn := &connection{
-		hostname: u.Hostname(),
-		port:     port,
-		proto:    u.Scheme,
-		pagesize: pagesize,
-		context:  context,
-	}
-
-	// attempt to login to the connection
-	pass, _ := u.User.Password()
-	url := fmt.Sprintf("%s://%s:%d/apiv2/login", conn.proto, conn.hostname, conn.port)
-	un := fmt.Sprintf(`{"userName":"%s","password":"%s"}`, u.User.Username(), pass)
-	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(un))
-	req.Header.Set("Content-Type", "application/json")
+// OpenConnector must parse the name in the same format that Driver.Open
+// parses the name parameter.
+func (d *db) OpenConnector(name string) (driver.Connector, error) {
+	cfg, err := ParseDSN(name)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
+	return NewConnector(*cfg)
+}
+
+func (c *connection) login(ctx context.Context) error {
+	type credentials struct {
+		UserName string `json:"userName"`
+		Password string `json:"password"`
 	}
-	defer resp.Body.Close()
 	type loginToken struct {
 		Token        string  `json:"token"`
 		ErrorMessage *string `json:"errorMessage,omitempty"`
 	}
+	buf, err := json.Marshal(credentials{c.username, c.password})
+	if err != nil {
+		return err
+	}
+	url := c.getAPIv2URL("/login")
 	var token loginToken
+	// logging in again is harmless
+	resp, err := c.retryable(ctx, http.MethodPost, url, buf, false, true)
+	if err != nil {
+		if serr, ok := err.(*StatusError); ok && json.Unmarshal([]byte(serr.Body), &token) == nil && token.ErrorMessage != nil {
+			return fmt.Errorf("error during login: %v", *token.ErrorMessage)
+		}
+		return err
+	}
+	defer resp.Body.Close()
 	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
-		return nil, fmt.Errorf("error decoding login token. %v", err)
+		return fmt.Errorf("error decoding login token. %v", err)
 	}
 	if token.ErrorMessage != nil {
-		return nil, fmt.Errorf("error during login: %v", *token.ErrorMessage)
+		return fmt.Errorf("error during login: %v", *token.ErrorMessage)
+	}
+	c.tokenMu.Lock()
+	c.token = token.Token
+	c.tokenMu.Unlock()
+	return nil
+}
+
+// retried notifies the observer of a retry, if it wants to know about them
+func (c *connection) retried(ctx context.Context, kind string, attempt int, err error) {
+	if o, ok := c.observer.(RetryObserver); ok {
+		o.Retried(ctx, RetryEvent{Endpoint: c.endpoint(), Kind: kind, Attempt: attempt, Err: err})
 	}
-	conn.token = token.Token
-	return conn, nil
 }
 
+func (c *connection) endpoint() string {
+	return fmt.Sprintf("%s:%d", c.hostname, c.port)
+}
+
+// getAPIURL returns the url of the path of the REST API, which is scoped
+// to the project with Dremio Cloud
+func (c *connection) getAPIURL(path string) string {
+	if c.project != "" {
+		return fmt.Sprintf("%s://%s:%d/v0/projects/%s%s", c.proto, c.hostname, c.port, c.project, path)
+	}
+	return fmt.Sprintf("%s://%s:%d/api/v3%s", c.proto, c.hostname, c.port, path)
+}
+
+// getAPIv2URL returns the url of the path of the older REST API used by the UI of Dremio Software
+func (c *connection) getAPIv2URL(path string) string {
+	return fmt.Sprintf("%s://%s:%d/apiv2%s", c.proto, c.hostname, c.port, path)
+}
+
+// getResultStatusURL returns the url of the job with the id. the job urls escape the id,
+// which may come from the caller, such as with Attach
 func (c *connection) getResultStatusURL(id string) string {
-	return fmt.Sprintf("%s://%s:%d/api/v3/job/%s", c.proto, c.hostname, c.port, id)
+	return c.getAPIURL(fmt.Sprintf("/job/%s", url.PathEscape(id)))
 }
 
 func (c *connection) getResultURL(id string, offset int, limit int) string {
-	return fmt.Sprintf("%s://%s:%d/api/v3/job/%s/results?offset=%d&limit=%d", c.proto, c.hostname, c.port, id, offset, limit)
+	return c.getAPIURL(fmt.Sprintf("/job/%s/results?offset=%d&limit=%d", url.PathEscape(id), offset, limit))
+}
+
+func (c *connection) getJobCancelURL(id string) string {
+	return c.getAPIURL(fmt.Sprintf("/job/%s/cancel", url.PathEscape(id)))
 }
 
 func (c *connection) getQueryURL() string {
-	return fmt.Sprintf("%s://%s:%d/api/v3/sql", c.proto, c.hostname, c.port)
+	return c.getAPIURL("/sql")
 }
 
-func (c *connection) post(url string, buf []byte) (*http.Response, error) {
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(buf))
-	if err != nil {
-		return nil, err
+// authorization returns the value of the Authorization header
+func (c *connection) authorization() string {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	if c.bearer {
+		return "Bearer " + c.token
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "_dremio"+c.token)
-	return http.DefaultClient.Do(req)
+	return "_dremio" + c.token
+}
+
+func (c *connection) get(ctx context.Context, url string) (*http.Response, error) {
+	return c.do(ctx, http.MethodGet, url, nil)
+}
+
+// do sends an authenticated request, logging in again once if the token has expired. POST
+// requests aren't idempotent, so they're only retried when they can't have been sent
+func (c *connection) do(ctx context.Context, method string, url string, buf []byte) (*http.Response, error) {
+	return c.send(ctx, method, url, buf, method != http.MethodPost)
 }
 
-func (c *connection) get(url string) (*http.Response, error) {
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+// send is like do, retrying the request on any transient failure if it's idempotent
+func (c *connection) send(ctx context.Context, method string, url string, buf []byte, idempotent bool) (*http.Response, error) {
+	resp, err := c.retryable(ctx, method, url, buf, true, idempotent)
+	if serr, ok := err.(*StatusError); ok && serr.StatusCode == http.StatusUnauthorized && c.username != "" && !c.bearer {
+		c.log.Info("token rejected, logging in again", "username", c.username)
+		err := c.login(ctx)
+		if o, ok := c.observer.(RetryObserver); ok {
+			o.Relogin(ctx, ReloginEvent{Endpoint: c.endpoint(), Err: err})
+		}
+		if err != nil {
+			c.log.Error("error logging in again", "username", c.username, "err", err)
+			return nil, err
+		}
+		return c.retryable(ctx, method, url, buf, true, idempotent)
+	}
+	return resp, err
+}
+
+// doJSON sends the value as JSON, if not nil, and decodes the response into out, if not nil
+func (c *connection) doJSON(ctx context.Context, method string, url string, in interface{}, out interface{}) error {
+	var buf []byte
+	if in != nil {
+		var err error
+		if buf, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	resp, err := c.do(ctx, method, url, buf)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decoding JSON response. %v", err)
+	}
+	return nil
+}
+
+// retryable sends a request, retrying it according to the retry policy. a request which isn't
+// idempotent is only retried when it can't have been processed: the connection couldn't be
+// established or the coordinator asked to slow down. a response is only returned for a
+// successful status code, otherwise a *StatusError is returned
+func (c *connection) retryable(ctx context.Context, method string, url string, buf []byte, auth bool, idempotent bool) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		var body io.Reader
+		if buf != nil {
+			body = bytes.NewReader(buf)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, body)
+		if err != nil {
+			return nil, err
+		}
+		if buf != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Accept", "application/json")
+		if auth {
+			req.Header.Set("Authorization", c.authorization())
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if !retryableError(err, idempotent) {
+				return nil, err
+			}
+		} else {
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				return resp, nil
+			}
+			rbuf, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			err = &StatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(rbuf))}
+			if !c.retry.retryableStatus(resp.StatusCode) || (!idempotent && resp.StatusCode != http.StatusTooManyRequests) {
+				return nil, err
+			}
+		}
+		if attempt >= c.retry.attempts() {
+			c.log.Error("request failed", "method", method, "url", url, "attempts", attempt, "err", err)
+			return nil, err
+		}
+		c.log.Warn("retrying request", "method", method, "url", url, "attempt", attempt, "err", err)
+		c.retried(ctx, RetryRequest, attempt, err)
+		if err := sleep(ctx, c.retry.backoff(attempt)); err != nil {
+			return nil, err
+		}
 	}
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", "_dremio"+c.token)
-	return http.DefaultClient.Do(req)
 }
 
 // Prepare returns a prepared statement, bound to this connection.
@@ -297,6 +518,9 @@ func (c *connection) PrepareContext(ctx context.Context, query string) (driver.S
 // idle connections, it shouldn't be necessary for drivers to
 // do their own connection caching.
 func (c *connection) Close() error {
+	if c.flight != nil {
+		return c.flight.close()
+	}
 	// don't shutdown the connection, we'll internally handle it
 	return nil
 }
@@ -396,7 +620,7 @@ func (c *connection) Query(query string, args []driver.Value) (driver.Rows, erro
 //
 // QueryerContext must honor the context timeout and return when the context is canceled.
 func (c *connection) QueryContext(ctx context.Context, rawQuery string, args []driver.NamedValue) (driver.Rows, error) {
-	q := query{rawQuery, c.context, nil}
+	q := c.newQuery(ctx, rawQuery, args)
 	return q.queryNamed(ctx, c, args)
 }
 
@@ -426,7 +650,7 @@ func (s *statement) NumInput() int {
 //
 // Deprecated: Drivers should implement StmtQueryContext instead (or additionally).
 func (s *statement) Query(args []driver.Value) (driver.Rows, error) {
-	q := query{s.query, s.conn.context, nil}
+	q := s.conn.newQuery(context.Background(), s.query, valueToNamedValue(args))
 	return q.query(context.Background(), s.conn, args)
 }
 
@@ -435,7 +659,7 @@ func (s *statement) Query(args []driver.Value) (driver.Rows, error) {
 //
 // QueryContext must honor the context timeout and return when it is canceled.
 func (s *statement) QueryContext(ctx context.Context, nargs []driver.NamedValue) (driver.Rows, error) {
-	q := query{s.query, s.conn.context, nil}
+	q := s.conn.newQuery(ctx, s.query, nargs)
 	return q.queryNamed(ctx, s.conn, nargs)
 }
 
@@ -467,6 +691,28 @@ func namedValueToValue(named []driver.NamedValue) ([]driver.Value, error) {
 	return dargs, nil
 }
 
+// valueToNamedValue is the inverse of namedValueToValue
+func valueToNamedValue(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for n, arg := range args {
+		named[n] = driver.NamedValue{Ordinal: n + 1, Value: arg}
+	}
+	return named
+}
+
+// convertArgs converts the args of the exported helpers which don't go through database/sql
+func convertArgs(args []interface{}) ([]driver.NamedValue, error) {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		val, err := driver.DefaultParameterConverter.ConvertValue(arg)
+		if err != nil {
+			return nil, fmt.Errorf("error converting argument %d. %v", i+1, err)
+		}
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: val}
+	}
+	return named, nil
+}
+
 // DriverName is the public name of the driver
 const DriverName = "dremio"
 
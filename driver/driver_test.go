@@ -84,3 +84,16 @@ func TestPlaceholderWithNoArg(t *testing.T) {
 	})
 	assert.Equal(`SELECT * FROM "foo" WHERE id = ?`, val)
 }
+
+func TestPlaceholderEscapesQuotes(t *testing.T) {
+	assert := assert.New(t)
+	q := `SELECT * FROM "foo" WHERE name = ?`
+	args := []string{"o'neil"}
+	val := replacePlaceholders(q, func(index int) driver.Value {
+		if index < len(args) {
+			return args[index]
+		}
+		return nil
+	})
+	assert.Equal(`SELECT * FROM "foo" WHERE name =  'o''neil' `, val)
+}
@@ -0,0 +1,326 @@
+package driver
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// the schemes which select the Arrow Flight transport instead of the REST job API
+const (
+	flightScheme    = "grpc"
+	flightTLSScheme = "grpc+tls"
+)
+
+const defaultFlightPort = 32010
+
+func isFlight(proto string) bool {
+	return proto == flightScheme || proto == flightTLSScheme
+}
+
+// flightClient runs queries using the Arrow Flight endpoint of the coordinator
+type flightClient struct {
+	client flight.Client
+	token  string
+}
+
+func newFlightClient(ctx context.Context, cfg *Config) (*flightClient, error) {
+	creds := insecure.NewCredentials()
+	if cfg.Proto == flightTLSScheme {
+		tlsConfig := cfg.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	addr := fmt.Sprintf("%s:%d", cfg.Hostname, cfg.Port)
+	client, err := flight.NewClientWithMiddleware(addr, nil, nil, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+	authctx, err := client.AuthenticateBasicToken(ctx, cfg.Username, cfg.Password)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("error during login: %v", err)
+	}
+	md, _ := metadata.FromOutgoingContext(authctx)
+	tokens := md.Get("authorization")
+	if len(tokens) == 0 {
+		client.Close()
+		return nil, fmt.Errorf("error during login: missing token")
+	}
+	return &flightClient{client: client, token: tokens[len(tokens)-1]}, nil
+}
+
+// outgoing returns the context with the token and the query context path as headers
+func (f *flightClient) outgoing(ctx context.Context, q query) context.Context {
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", f.token)
+	if len(q.Context) > 0 {
+		// dremio uses the schema property as the default path of the query
+		ctx = metadata.AppendToOutgoingContext(ctx, "schema", strings.Join(quoteContext(q.Context), "."))
+	}
+	return ctx
+}
+
+func quoteContext(path []string) []string {
+	quoted := make([]string, len(path))
+	for i, p := range path {
+		quoted[i] = `"` + strings.Replace(p, `"`, `""`, -1) + `"`
+	}
+	return quoted
+}
+
+func (f *flightClient) query(ctx context.Context, c *connection, q query) (driver.Rows, error) {
+	info := &QueryInfo{
+		SQL:      q.Query,
+		Args:     q.args,
+		Context:  q.Context,
+		Endpoint: c.endpoint(),
+		Started:  time.Now(),
+	}
+	ctx = c.observer.QuerySubmitted(ctx, info)
+	if len(q.References) > 0 {
+		err := fmt.Errorf("references aren't supported with Arrow Flight")
+		c.observer.QueryFailed(ctx, info, QueryFailedEvent{Err: err, Duration: time.Since(info.Started)})
+		return nil, err
+	}
+	release, err := c.acquireJob(ctx, info)
+	if err != nil {
+		c.observer.QueryFailed(ctx, info, QueryFailedEvent{Err: err, Duration: time.Since(info.Started)})
+		return nil, err
+	}
+	rows, err := f.start(ctx, c, q.bind(q.args), info)
+	if err != nil {
+		release()
+		c.observer.QueryFailed(ctx, info, QueryFailedEvent{Err: err, Duration: time.Since(info.Started)})
+		return nil, err
+	}
+	// the job runs until its stream has been read
+	rows.release = release
+	return rows, nil
+}
+
+func (f *flightClient) start(ctx context.Context, c *connection, q query, info *QueryInfo) (*flightRows, error) {
+	ctx, cancel := context.WithCancel(f.outgoing(ctx, q))
+	fi, err := f.client.GetFlightInfo(ctx, &flight.FlightDescriptor{
+		Type: flight.DescriptorCMD,
+		Cmd:  []byte(q.Query),
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	schema, err := flight.DeserializeSchema(fi.Schema, nil)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("error decoding flight schema. %v", err)
+	}
+	rows := &flightRows{
+		ctx:       ctx,
+		cancel:    cancel,
+		client:    f.client,
+		conn:      c,
+		info:      info,
+		schema:    schema,
+		endpoints: fi.Endpoint,
+	}
+	return rows, nil
+}
+
+func (f *flightClient) close() error {
+	return f.client.Close()
+}
+
+// flightRows streams the record batches of each flight endpoint in order
+type flightRows struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	release   func()
+	client    flight.Client
+	conn      *connection
+	info      *QueryInfo
+	schema    *arrow.Schema
+	endpoints []*flight.FlightEndpoint
+	reader    *flight.Reader
+	record    arrow.Record
+	index     int
+	read      int
+	offset    int
+	done      bool
+}
+
+var _ driver.Rows = (*flightRows)(nil)
+
+// Columns returns the names of the columns.
+func (r *flightRows) Columns() []string {
+	names := make([]string, len(r.schema.Fields()))
+	for i, f := range r.schema.Fields() {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// Close closes the rows iterator, cancelling the stream if it wasn't read until the end.
+func (r *flightRows) Close() error {
+	r.complete(nil)
+	return nil
+}
+
+func (r *flightRows) complete(err error) {
+	if r.done {
+		return
+	}
+	r.done = true
+	if r.reader != nil {
+		r.reader.Release()
+		r.reader = nil
+	}
+	r.cancel()
+	if r.release != nil {
+		r.release()
+	}
+	if err != nil {
+		r.conn.observer.QueryFailed(r.ctx, r.info, QueryFailedEvent{Err: err, Duration: time.Since(r.info.Started)})
+		return
+	}
+	r.conn.observer.QueryCompleted(r.ctx, r.info, QueryCompletedEvent{Rows: r.read, Duration: time.Since(r.info.Started)})
+}
+
+// nextRecord advances to the next record batch, opening the stream of the next endpoint when needed
+func (r *flightRows) nextRecord() (bool, error) {
+	for {
+		if r.reader == nil {
+			if len(r.endpoints) == 0 {
+				return false, nil
+			}
+			stream, err := r.client.DoGet(r.ctx, r.endpoints[0].Ticket)
+			if err != nil {
+				return false, err
+			}
+			r.endpoints = r.endpoints[1:]
+			r.reader, err = flight.NewRecordReader(stream)
+			if err != nil {
+				return false, err
+			}
+		}
+		started := time.Now()
+		if r.reader.Next() {
+			r.record = r.reader.RecordBatch()
+			r.index = 0
+			r.conn.observer.PageFetched(r.ctx, r.info, PageEvent{
+				Offset:   r.offset,
+				Rows:     int(r.record.NumRows()),
+				Duration: time.Since(started),
+			})
+			r.offset += int(r.record.NumRows())
+			return true, nil
+		}
+		err := r.reader.Err()
+		r.reader.Release()
+		r.reader = nil
+		r.record = nil
+		if err != nil && err != io.EOF {
+			return false, err
+		}
+	}
+}
+
+// Next is called to populate the next row of data into
+// the provided slice. The provided slice will be the same
+// size as the Columns() are wide.
+//
+// Next should return io.EOF when there are no more rows.
+func (r *flightRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	for r.record == nil || int64(r.index) >= r.record.NumRows() {
+		ok, err := r.nextRecord()
+		if err != nil {
+			r.complete(err)
+			return err
+		}
+		if !ok {
+			r.complete(nil)
+			return io.EOF
+		}
+	}
+	if len(dest) != int(r.record.NumCols()) {
+		return fmt.Errorf("invalid scan, expected %d arguments and received %d", r.record.NumCols(), len(dest))
+	}
+	for i, col := range r.record.Columns() {
+		dest[i] = arrowValue(col, r.index)
+	}
+	r.index++
+	r.read++
+	return nil
+}
+
+// arrowValue returns the value at the index of the array as a driver value
+func arrowValue(arr arrow.Array, i int) driver.Value {
+	if arr.IsNull(i) {
+		return nil
+	}
+	switch a := arr.(type) {
+	case *array.Boolean:
+		return a.Value(i)
+	case *array.Int8:
+		return int64(a.Value(i))
+	case *array.Int16:
+		return int64(a.Value(i))
+	case *array.Int32:
+		return int64(a.Value(i))
+	case *array.Int64:
+		return a.Value(i)
+	case *array.Uint8:
+		return int64(a.Value(i))
+	case *array.Uint16:
+		return int64(a.Value(i))
+	case *array.Uint32:
+		return int64(a.Value(i))
+	case *array.Uint64:
+		return int64(a.Value(i))
+	case *array.Float16:
+		return float64(a.Value(i).Float32())
+	case *array.Float32:
+		return float64(a.Value(i))
+	case *array.Float64:
+		return a.Value(i)
+	case *array.String:
+		return a.Value(i)
+	case *array.LargeString:
+		return a.Value(i)
+	case *array.Binary:
+		return append([]byte(nil), a.Value(i)...)
+	case *array.LargeBinary:
+		return append([]byte(nil), a.Value(i)...)
+	case *array.FixedSizeBinary:
+		return append([]byte(nil), a.Value(i)...)
+	case *array.Decimal128:
+		// keep the precision of the decimal, it can still be scanned into a float64
+		return a.Value(i).ToString(a.DataType().(*arrow.Decimal128Type).Scale)
+	case *array.Date32:
+		return a.Value(i).ToTime().UTC()
+	case *array.Date64:
+		return a.Value(i).ToTime().UTC()
+	case *array.Timestamp:
+		return a.Value(i).ToTime(a.DataType().(*arrow.TimestampType).Unit).UTC()
+	case *array.Time32:
+		return a.Value(i).ToTime(a.DataType().(*arrow.Time32Type).Unit).Format("15:04:05.000")
+	case *array.Time64:
+		return a.Value(i).ToTime(a.DataType().(*arrow.Time64Type).Unit).Format("15:04:05.000000")
+	}
+	return arr.GetOneForMarshal(i)
+}
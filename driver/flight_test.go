@@ -0,0 +1,126 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type testFlightAuth struct{}
+
+func (testFlightAuth) Validate(username, password string) (string, error) {
+	if username == "user" && password == "pass" {
+		return "token", nil
+	}
+	return "", status.Error(codes.Unauthenticated, "invalid user/password")
+}
+
+func (testFlightAuth) IsValid(token string) (interface{}, error) {
+	if token == "token" {
+		return "user", nil
+	}
+	return nil, status.Error(codes.Unauthenticated, "invalid token")
+}
+
+// testFlightServer stands in for the flight endpoint of a coordinator, it returns
+// two endpoints with a batch of two rows each
+type testFlightServer struct {
+	flight.BaseFlightServer
+	schema  *arrow.Schema
+	queries []string
+	schemas []string
+}
+
+func (s *testFlightServer) GetFlightInfo(ctx context.Context, desc *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	s.queries = append(s.queries, string(desc.Cmd))
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.schemas = append(s.schemas, strings.Join(md.Get("schema"), ""))
+	return &flight.FlightInfo{
+		Schema:           flight.SerializeSchema(s.schema, memory.DefaultAllocator),
+		FlightDescriptor: desc,
+		Endpoint: []*flight.FlightEndpoint{
+			{Ticket: &flight.Ticket{Ticket: []byte("0")}},
+			{Ticket: &flight.Ticket{Ticket: []byte("2")}},
+		},
+	}, nil
+}
+
+func (s *testFlightServer) DoGet(tkt *flight.Ticket, stream flight.FlightService_DoGetServer) error {
+	b := array.NewRecordBuilder(memory.DefaultAllocator, s.schema)
+	defer b.Release()
+	start := int64(tkt.Ticket[0] - '0')
+	b.Field(0).(*array.Int64Builder).AppendValues([]int64{start, start + 1}, nil)
+	b.Field(1).(*array.StringBuilder).AppendValues([]string{"a", ""}, []bool{true, false})
+	rec := b.NewRecord()
+	defer rec.Release()
+	w := flight.NewRecordWriter(stream, ipc.WithSchema(s.schema))
+	defer w.Close()
+	return w.Write(rec)
+}
+
+func TestFlightQuery(t *testing.T) {
+	assert := assert.New(t)
+	fs := &testFlightServer{
+		schema: arrow.NewSchema([]arrow.Field{
+			{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+			{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+		}, nil),
+	}
+	srv := flight.NewServerWithMiddleware([]flight.ServerMiddleware{flight.CreateServerBasicAuthMiddleware(testFlightAuth{})})
+	assert.NoError(srv.Init("localhost:0"))
+	srv.RegisterFlightService(fs)
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	db, err := sql.Open(DriverName, "grpc://user:pass@"+srv.Addr().String()+`?context=Samples."samples.dremio.com"`)
+	assert.NoError(err)
+	defer db.Close()
+	rows, err := db.Query("SELECT id, name FROM foo WHERE id > ?", 0)
+	assert.NoError(err)
+	cols, err := rows.Columns()
+	assert.NoError(err)
+	assert.Equal([]string{"id", "name"}, cols)
+	var ids []int64
+	var names []sql.NullString
+	for rows.Next() {
+		var id int64
+		var name sql.NullString
+		assert.NoError(rows.Scan(&id, &name))
+		ids = append(ids, id)
+		names = append(names, name)
+	}
+	assert.NoError(rows.Err())
+	assert.NoError(rows.Close())
+	assert.Equal([]int64{0, 1, 2, 3}, ids)
+	assert.Equal(sql.NullString{String: "a", Valid: true}, names[0])
+	assert.False(names[1].Valid)
+	assert.Equal([]string{"SELECT id, name FROM foo WHERE id >  0  "}, fs.queries)
+	assert.Equal([]string{`"Samples"."samples.dremio.com"`}, fs.schemas)
+}
+
+func TestFlightLoginError(t *testing.T) {
+	assert := assert.New(t)
+	srv := flight.NewServerWithMiddleware([]flight.ServerMiddleware{flight.CreateServerBasicAuthMiddleware(testFlightAuth{})})
+	assert.NoError(srv.Init("localhost:0"))
+	srv.RegisterFlightService(&testFlightServer{})
+	go srv.Serve()
+	defer srv.Shutdown()
+
+	db, err := sql.Open(DriverName, "grpc://user:wrong@"+srv.Addr().String())
+	assert.NoError(err)
+	defer db.Close()
+	err = db.Ping()
+	assert.Error(err)
+	assert.Contains(err.Error(), "error during login")
+}
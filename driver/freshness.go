@@ -0,0 +1,146 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pinpt/go-dremio/dremiosql"
+)
+
+// defaultRefreshPollInterval is how often the reflections are checked while waiting for them to refresh
+const defaultRefreshPollInterval = time.Second
+
+// FreshOptions are the options of EnsureFresh
+type FreshOptions struct {
+	// RefreshMetadata refreshes the metadata of the dataset before its reflections, so that
+	// new files of a source are seen
+	RefreshMetadata bool
+	// ReflectionIDs are more reflections to wait for besides the enabled reflections of the dataset,
+	// such as the reflections of the views built on it
+	ReflectionIDs []string
+	// PollInterval is how often the reflections are checked. defaults to a second
+	PollInterval time.Duration
+}
+
+// RefreshError is returned when a reflection can't be refreshed, such as when Dremio gave up
+// refreshing it after too many failures
+type RefreshError struct {
+	ReflectionID string
+	Status       ReflectionStatus
+}
+
+func (e *RefreshError) Error() string {
+	return fmt.Sprintf("reflection %s can't be refreshed. config: %s, refresh: %s, failures: %d", e.ReflectionID, e.Status.Config, e.Status.Refresh, e.Status.FailureCount)
+}
+
+// Refresh triggers the refresh of the reflections which depend on the physical dataset with the id
+func (r *Reflections) Refresh(ctx context.Context, datasetID string) error {
+	if datasetID == "" {
+		return fmt.Errorf("missing dataset id")
+	}
+	if err := r.conn.doJSON(ctx, http.MethodPost, r.conn.getAPIURL("/catalog/"+url.PathEscape(datasetID)+"/refresh"), nil, nil); err != nil {
+		return err
+	}
+	r.conn.log.Info("refreshing reflections", "dataset", datasetID)
+	return nil
+}
+
+// RefreshMetadata refreshes the metadata of the physical dataset at the path and waits for it to complete
+func (r *Reflections) RefreshMetadata(ctx context.Context, path ...string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("missing dataset path")
+	}
+	q := query{Query: "ALTER TABLE " + dremiosql.QuotePath(path...) + " REFRESH METADATA"}
+	buf, err := q.buildNamed(nil)
+	if err != nil {
+		return err
+	}
+	// run it like any other query, so it's limited, observed and retried
+	rows, err := q.send(ctx, r.conn, buf)
+	if err != nil {
+		return err
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	r.conn.log.Info("refreshed metadata", "dataset", dremiosql.QuotePath(path...))
+	return nil
+}
+
+// WaitForRefresh blocks until each of the reflections with the ids has data fetched after since,
+// checking them every interval. It returns a *RefreshError if one of them can't be refreshed.
+// since is compared to the clock of the coordinator, so allow for some skew
+func (r *Reflections) WaitForRefresh(ctx context.Context, since time.Time, interval time.Duration, ids ...string) error {
+	if interval <= 0 {
+		interval = defaultRefreshPollInterval
+	}
+	pending := ids
+	for {
+		var stale []string
+		for _, id := range pending {
+			status, err := r.Status(ctx, id)
+			if err != nil {
+				return err
+			}
+			if status.Config == "INVALID" || status.Refresh == "GIVEN_UP" {
+				return &RefreshError{ReflectionID: id, Status: *status}
+			}
+			if status.LastDataFetch == nil || !status.LastDataFetch.After(since) || status.Availability != "AVAILABLE" {
+				stale = append(stale, id)
+			}
+		}
+		if len(stale) == 0 {
+			return nil
+		}
+		r.conn.log.Debug("waiting for reflections to refresh", "reflections", stale, "since", since)
+		pending = stale
+		if err := sleep(ctx, interval); err != nil {
+			return err
+		}
+	}
+}
+
+// EnsureFresh refreshes the reflections of the physical dataset with the id, and its metadata first if
+// asked to, then blocks until the reflections have data fetched after since, such as the time the
+// dataset was last written. Queries which run afterwards aren't accelerated with stale data
+func (r *Reflections) EnsureFresh(ctx context.Context, datasetID string, since time.Time, opts FreshOptions) error {
+	if opts.RefreshMetadata {
+		catalog := &Catalog{conn: r.conn}
+		dataset, err := catalog.Get(ctx, datasetID)
+		if err != nil {
+			return err
+		}
+		if err := r.RefreshMetadata(ctx, dataset.Path...); err != nil {
+			return err
+		}
+	}
+	reflections, err := r.ListForDataset(ctx, datasetID)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	seen := make(map[string]bool)
+	for _, reflection := range reflections {
+		if reflection.Enabled && !seen[reflection.ID] {
+			seen[reflection.ID] = true
+			ids = append(ids, reflection.ID)
+		}
+	}
+	for _, id := range opts.ReflectionIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if err := r.Refresh(ctx, datasetID); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		// nothing accelerates the dataset
+		return nil
+	}
+	return r.WaitForRefresh(ctx, since, opts.PollInterval, ids...)
+}
@@ -0,0 +1,83 @@
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnsureFresh(t *testing.T) {
+	assert := assert.New(t)
+	written := time.Date(2019, 6, 11, 19, 0, 0, 0, time.UTC)
+	var polls, refreshes int32
+	var sql string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /apiv2/login":
+			io.WriteString(w, `{"token":"abc"}`)
+		case "GET /api/v3/catalog/d1":
+			io.WriteString(w, `{"entityType":"dataset","id":"d1","type":"PHYSICAL_DATASET","path":["lake","2019","orders"]}`)
+		case "POST /api/v3/sql":
+			var q query
+			json.NewDecoder(r.Body).Decode(&q)
+			sql = q.Query
+			io.WriteString(w, `{"id":"j1"}`)
+		case "GET /api/v3/job/j1":
+			io.WriteString(w, `{"jobState":"COMPLETED","rowCount":1}`)
+		case "GET /api/v3/job/j1/results":
+			io.WriteString(w, `{"rowCount":1,"schema":[{"name":"ok","type":{"name":"BOOLEAN"}}],"rows":[{"ok":true}]}`)
+		case "GET /api/v3/dataset/d1/reflection":
+			io.WriteString(w, `{"data":[{"id":"r1","enabled":true},{"id":"r2","enabled":false}]}`)
+		case "POST /api/v3/catalog/d1/refresh":
+			atomic.AddInt32(&refreshes, 1)
+		case "GET /api/v3/reflection/r1":
+			fetched := written.Add(-time.Hour)
+			if atomic.AddInt32(&polls, 1) > 2 {
+				fetched = written.Add(time.Minute)
+			}
+			fmt.Fprintf(w, `{"id":"r1","status":{"config":"OK","refresh":"SCHEDULED","availability":"AVAILABLE","lastDataFetch":%q}}`, fetched.Format(time.RFC3339))
+		case "GET /api/v3/reflection/r3":
+			io.WriteString(w, `{"id":"r3","status":{"config":"OK","refresh":"GIVEN_UP","availability":"EXPIRED","failureCount":3}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	c := newTestConnector(t, srv)
+	observer := &testObserver{}
+	c.cfg.Observer = observer
+	reflections, err := c.Reflections(ctx)
+	assert.NoError(err)
+
+	assert.NoError(reflections.EnsureFresh(ctx, "d1", written, FreshOptions{RefreshMetadata: true, PollInterval: time.Millisecond}))
+	assert.Equal(`ALTER TABLE lake."2019".orders REFRESH METADATA`, sql)
+	assert.Equal(int32(1), atomic.LoadInt32(&refreshes))
+	assert.Equal(int32(3), atomic.LoadInt32(&polls))
+	// the metadata is refreshed like any other query
+	assert.Contains(observer.events, `submitted ALTER TABLE lake."2019".orders REFRESH METADATA`)
+	assert.Contains(observer.events, "created j1")
+	assert.Equal("completed 0", observer.events[len(observer.events)-1])
+
+	err = reflections.EnsureFresh(ctx, "d1", written, FreshOptions{ReflectionIDs: []string{"r3"}, PollInterval: time.Millisecond})
+	var rerr *RefreshError
+	assert.True(errors.As(err, &rerr))
+	assert.Equal("r3", rerr.ReflectionID)
+	assert.Equal(3, rerr.Status.FailureCount)
+	// the enabled reflections of the dataset are waited for too
+	assert.Equal(int32(4), atomic.LoadInt32(&polls))
+
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	err = reflections.WaitForRefresh(ctx, written.Add(time.Hour), time.Millisecond, "r1")
+	assert.Equal(context.DeadlineExceeded, err)
+}
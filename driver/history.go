@@ -0,0 +1,283 @@
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultJobHistoryLimit is the number of jobs listed by default
+const defaultJobHistoryLimit = 100
+
+// JobFilter restricts the jobs listed by JobHistory.List. Each field matches any of its values,
+// and the zero value matches all the jobs
+type JobFilter struct {
+	// Users are the names of the users who ran the jobs
+	Users []string
+	// States are the states of the jobs, such as RUNNING, COMPLETED, FAILED or CANCELED
+	States []string
+	// Datasets are the paths of the datasets the jobs queried, joined by dots
+	Datasets []string
+	// QueryTypes are the sources of the jobs, such as UI, EXTERNAL, ACCELERATION, INTERNAL or DOWNLOAD
+	QueryTypes []string
+	// From and To are the range of the start time of the jobs
+	From time.Time
+	To   time.Time
+	// Contains is text the jobs must contain, such as in their SQL
+	Contains string
+	// Offset is the number of jobs to skip, newest first
+	Offset int
+	// Limit is the maximum number of jobs to list. defaults to 100
+	Limit int
+}
+
+// JobSummary is a job listed by JobHistory.List
+type JobSummary struct {
+	ID              string       `json:"id"`
+	State           string       `json:"state"`
+	User            string       `json:"user"`
+	QueryType       string       `json:"queryType"`
+	SQL             string       `json:"queryText"`
+	Queue           string       `json:"wlmQueue"`
+	Engine          string       `json:"engine"`
+	Accelerated     bool         `json:"accelerated"`
+	RowsScanned     int64        `json:"rowsScanned"`
+	RowCount        int64        `json:"outputRecords"`
+	QueriedDatasets []JobDataset `json:"queriedDatasets"`
+	StartedAt       time.Time    `json:"-"`
+	EndedAt         time.Time    `json:"-"`
+}
+
+// UnmarshalJSON decodes a job listed by the REST API of the UI, whose start and end times are
+// milliseconds since the epoch, sent as numbers or strings
+func (s *JobSummary) UnmarshalJSON(buf []byte) error {
+	type summary JobSummary
+	v := struct {
+		*summary
+		StartTime epochMillis `json:"startTime"`
+		EndTime   epochMillis `json:"endTime"`
+	}{summary: (*summary)(s)}
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return err
+	}
+	s.StartedAt, s.EndedAt = v.StartTime.time(), v.EndTime.time()
+	return nil
+}
+
+// JobDataset is a dataset queried by a job
+type JobDataset struct {
+	Name string   `json:"datasetName"`
+	Path []string `json:"datasetPathsList"`
+	Type string   `json:"datasetType"`
+}
+
+// JobPage is a page of jobs listed by JobHistory.List
+type JobPage struct {
+	Jobs []JobSummary
+	// Next is the filter of the next page, nil for the last page
+	Next *JobFilter
+}
+
+// JobDetails are the details of a job
+type JobDetails struct {
+	JobStatus
+	User      string
+	QueryType string
+	SQL       string
+	Queue     string
+	Engine    string
+	// CancellationReason is why a cancelled job was cancelled
+	CancellationReason string
+	// Reflections are the reflections considered to accelerate the job
+	Reflections []ReflectionRelationship
+	// Phases are the time spent in each phase of the job, such as PENDING, PLANNING, QUEUED or RUNNING
+	Phases []JobPhase
+}
+
+// ReflectionRelationship is how a reflection was considered to accelerate a job
+type ReflectionRelationship struct {
+	DatasetID    string `json:"datasetId"`
+	ReflectionID string `json:"reflectionId"`
+	// Relationship is CONSIDERED, MATCHED or CHOSEN for a reflection which accelerated the job
+	Relationship string `json:"relationship"`
+}
+
+// JobPhase is a phase of a job
+type JobPhase struct {
+	Name      string
+	StartedAt time.Time
+	Duration  time.Duration
+}
+
+// jobDetails are the details of a job returned with its state by the REST API
+type jobDetails struct {
+	jobState
+	QueryType          string `json:"queryType"`
+	QueueName          string `json:"queueName"`
+	CancellationReason string `json:"cancellationReason"`
+	Acceleration       struct {
+		ReflectionRelationships []ReflectionRelationship `json:"reflectionRelationships"`
+	} `json:"acceleration"`
+}
+
+// jobInfo are the details of a job returned by the REST API of the UI
+type jobInfo struct {
+	User            string `json:"queryUser"`
+	SQL             string `json:"queryText"`
+	Queue           string `json:"wlmQueue"`
+	Engine          string `json:"engine"`
+	DurationDetails []struct {
+		Name      string      `json:"phaseName"`
+		StartedAt epochMillis `json:"phaseStartTime"`
+		Duration  epochMillis `json:"phaseDuration"`
+	} `json:"durationDetails"`
+}
+
+// epochMillis is a number of milliseconds, sent as a number or a string
+type epochMillis int64
+
+func (m *epochMillis) UnmarshalJSON(buf []byte) error {
+	s := strings.Trim(string(buf), `"`)
+	if s == "" || s == "null" {
+		*m = 0
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid milliseconds %s", buf)
+	}
+	*m = epochMillis(v)
+	return nil
+}
+
+// time returns the milliseconds since the epoch as a time, the zero time for 0
+func (m epochMillis) time() time.Time {
+	if m == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(m)).UTC()
+}
+
+// JobHistory is a client for the job history of Dremio Software, sharing the configuration and
+// authentication of the connector
+type JobHistory struct {
+	conn *connection
+}
+
+// JobHistory returns a client for the job history. Only the REST API has the job history, so it
+// returns an error for the grpc schemes
+func (c *Connector) JobHistory(ctx context.Context) (*JobHistory, error) {
+	conn, err := c.restConnection(ctx, "job histories")
+	if err != nil {
+		return nil, err
+	}
+	return &JobHistory{conn: conn}, nil
+}
+
+// encode returns the filter in the syntax of the job listing, such as (usr=="dremio");(jst=="FAILED")
+func (f JobFilter) encode() string {
+	var clauses []string
+	in := func(key string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		quoted := make([]string, len(values))
+		for i, v := range values {
+			quoted[i] = strconv.Quote(v)
+		}
+		clauses = append(clauses, "("+key+"=="+strings.Join(quoted, ",")+")")
+	}
+	in("usr", f.Users)
+	in("jst", f.States)
+	in("ads", f.Datasets)
+	in("qt", f.QueryTypes)
+	if !f.From.IsZero() {
+		clauses = append(clauses, fmt.Sprintf("(st=gt=%d)", f.From.UnixMilli()))
+	}
+	if !f.To.IsZero() {
+		clauses = append(clauses, fmt.Sprintf("(st=lt=%d)", f.To.UnixMilli()))
+	}
+	if f.Contains != "" {
+		clauses = append(clauses, "(*=contains="+strconv.Quote(f.Contains)+")")
+	}
+	return strings.Join(clauses, ";")
+}
+
+// List returns a page of the jobs matching the filter, newest first. The job history is only
+// available with Dremio Software
+func (h *JobHistory) List(ctx context.Context, filter JobFilter) (*JobPage, error) {
+	if h.conn.project != "" {
+		return nil, fmt.Errorf("the job history isn't supported with dremio cloud")
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultJobHistoryLimit
+	}
+	params := url.Values{}
+	if f := filter.encode(); f != "" {
+		params.Set("filter", f)
+	}
+	params.Set("sort", "st")
+	params.Set("order", "DESCENDING")
+	params.Set("offset", strconv.Itoa(filter.Offset))
+	params.Set("limit", strconv.Itoa(filter.Limit))
+	params.Set("detailLevel", "1")
+	var res struct {
+		Jobs []JobSummary `json:"jobs"`
+		Next string       `json:"next"`
+	}
+	if err := h.conn.doJSON(ctx, http.MethodGet, h.conn.getAPIv2URL("/jobs-listing/v1.0?"+params.Encode()), nil, &res); err != nil {
+		return nil, err
+	}
+	page := &JobPage{Jobs: res.Jobs}
+	if res.Next != "" && len(res.Jobs) > 0 {
+		next := filter
+		next.Offset += len(res.Jobs)
+		page.Next = &next
+	}
+	return page, nil
+}
+
+// Details returns the details of the job with the id. With Dremio Cloud, only the details
+// of its state and acceleration are returned
+func (h *JobHistory) Details(ctx context.Context, id string) (*JobDetails, error) {
+	if id == "" {
+		return nil, fmt.Errorf("missing job id")
+	}
+	var state jobDetails
+	if err := h.conn.doJSON(ctx, http.MethodGet, h.conn.getResultStatusURL(id), nil, &state); err != nil {
+		return nil, err
+	}
+	details := &JobDetails{
+		JobStatus:          *newJobStatus(id, &state.jobState),
+		QueryType:          state.QueryType,
+		Queue:              state.QueueName,
+		CancellationReason: state.CancellationReason,
+		Reflections:        state.Acceleration.ReflectionRelationships,
+	}
+	if h.conn.project != "" {
+		return details, nil
+	}
+	var info jobInfo
+	if err := h.conn.doJSON(ctx, http.MethodGet, h.conn.getAPIv2URL("/jobs-listing/v1.0/"+url.PathEscape(id)+"/jobDetails?detailLevel=1"), nil, &info); err != nil {
+		return nil, err
+	}
+	details.User = info.User
+	details.SQL = info.SQL
+	details.Engine = info.Engine
+	if details.Queue == "" {
+		details.Queue = info.Queue
+	}
+	for _, phase := range info.DurationDetails {
+		details.Phases = append(details.Phases, JobPhase{
+			Name:      phase.Name,
+			StartedAt: phase.StartedAt.time(),
+			Duration:  time.Duration(phase.Duration) * time.Millisecond,
+		})
+	}
+	return details, nil
+}
@@ -0,0 +1,99 @@
+package driver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobHistory(t *testing.T) {
+	assert := assert.New(t)
+	var params, escaped []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		escaped = append(escaped, r.URL.EscapedPath())
+		switch r.Method + " " + r.URL.Path {
+		case "POST /apiv2/login":
+			io.WriteString(w, `{"token":"abc"}`)
+		case "GET /apiv2/jobs-listing/v1.0":
+			params = append(params, r.URL.RawQuery)
+			if r.URL.Query().Get("offset") == "0" {
+				io.WriteString(w, `{"jobs":[
+					{"id":"j2","state":"FAILED","user":"etl","queryType":"EXTERNAL","queryText":"SELECT * FROM sales.orders","startTime":1560279600000,"endTime":"1560279601500",
+					 "queriedDatasets":[{"datasetName":"orders","datasetPathsList":["sales","orders"],"datasetType":"VIRTUAL_DATASET"}]},
+					{"id":"j1","state":"COMPLETED","user":"etl","queryType":"EXTERNAL","queryText":"SELECT 1","startTime":1560279500000,"endTime":1560279500100,"accelerated":true}
+				],"next":"/jobs-listing/v1.0?offset=2"}`)
+				return
+			}
+			io.WriteString(w, `{"jobs":[]}`)
+		case "GET /api/v3/job/j2":
+			io.WriteString(w, `{"jobState":"FAILED","rowCount":0,"errorMessage":"VALIDATION ERROR: Object 'orders' not found","startedAt":"2019-06-11T19:00:00.000Z","endedAt":"2019-06-11T19:00:01.500Z",
+				"queryType":"REST","queueName":"Low Cost User Queries","acceleration":{"reflectionRelationships":[{"datasetId":"d1","reflectionId":"r1","relationship":"CONSIDERED"}]}}`)
+		case "GET /apiv2/jobs-listing/v1.0/j2/jobDetails":
+			io.WriteString(w, `{"queryUser":"etl","queryText":"SELECT * FROM sales.orders","engine":"default","durationDetails":[
+				{"phaseName":"PLANNING","phaseStartTime":"1560279600000","phaseDuration":"1200"},
+				{"phaseName":"RUNNING","phaseStartTime":1560279601200,"phaseDuration":300}
+			]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	history, err := newTestConnector(t, srv).JobHistory(ctx)
+	assert.NoError(err)
+
+	page, err := history.List(ctx, JobFilter{
+		Users:    []string{"etl"},
+		States:   []string{"FAILED", "COMPLETED"},
+		Datasets: []string{"sales.orders"},
+		From:     time.Date(2019, 6, 11, 0, 0, 0, 0, time.UTC),
+		Limit:    2,
+	})
+	assert.NoError(err)
+	assert.Len(page.Jobs, 2)
+	assert.Equal("SELECT * FROM sales.orders", page.Jobs[0].SQL)
+	assert.Equal([]string{"sales", "orders"}, page.Jobs[0].QueriedDatasets[0].Path)
+	assert.Equal(time.Date(2019, 6, 11, 19, 0, 0, 0, time.UTC), page.Jobs[0].StartedAt)
+	assert.Equal(1500*time.Millisecond, page.Jobs[0].EndedAt.Sub(page.Jobs[0].StartedAt))
+	assert.True(page.Jobs[1].Accelerated)
+	assert.Contains(params[0], "filter=%28usr%3D%3D%22etl%22%29%3B%28jst%3D%3D%22FAILED%22%2C%22COMPLETED%22%29%3B%28ads%3D%3D%22sales.orders%22%29%3B%28st%3Dgt%3D1560211200000%29")
+	assert.NotNil(page.Next)
+	assert.Equal(2, page.Next.Offset)
+
+	page, err = history.List(ctx, *page.Next)
+	assert.NoError(err)
+	assert.Empty(page.Jobs)
+	assert.Nil(page.Next)
+
+	details, err := history.Details(ctx, "j2")
+	assert.NoError(err)
+	assert.Equal("FAILED", details.State)
+	assert.Equal("VALIDATION ERROR: Object 'orders' not found", details.ErrorMessage)
+	assert.Equal("Low Cost User Queries", details.Queue)
+	assert.Equal("SELECT * FROM sales.orders", details.SQL)
+	assert.Equal("etl", details.User)
+	assert.Equal("r1", details.Reflections[0].ReflectionID)
+	assert.Equal([]JobPhase{
+		{Name: "PLANNING", StartedAt: time.Date(2019, 6, 11, 19, 0, 0, 0, time.UTC), Duration: 1200 * time.Millisecond},
+		{Name: "RUNNING", StartedAt: time.Date(2019, 6, 11, 19, 0, 1, 200000000, time.UTC), Duration: 300 * time.Millisecond},
+	}, details.Phases)
+
+	_, err = history.Details(ctx, "a/b")
+	assert.Error(err)
+	assert.Equal("/api/v3/job/a%2Fb", escaped[len(escaped)-1])
+}
+
+func TestJobFilterEncode(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("", JobFilter{}.encode())
+	assert.Equal(`(qt=="UI","EXTERNAL");(st=lt=1000);(*=contains="say \"hi\"")`, JobFilter{
+		QueryTypes: []string{"UI", "EXTERNAL"},
+		To:         time.UnixMilli(1000),
+		Contains:   `say "hi"`,
+	}.encode())
+}
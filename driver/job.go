@@ -0,0 +1,207 @@
+package driver
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Job is a query running in Dremio which isn't tied to a goroutine or a pooled connection.
+// Use Submit to start one and Attach to pick up a job started elsewhere, such as by
+// another process before a restart. A job only holds a slot of Config.MaxConcurrentJobs
+// while it's submitted, so it can be left running or picked up elsewhere without holding
+// back the queries of the connector
+type Job struct {
+	// ID is the id of the job in Dremio
+	ID string
+
+	conn *connection
+	info *QueryInfo
+	done sync.Once
+}
+
+// JobStatus is the state of a job
+type JobStatus struct {
+	ID           string
+	State        string
+	RowCount     int
+	ErrorMessage string
+	StartedAt    time.Time
+	EndedAt      time.Time
+}
+
+// Done returns true if the job has completed, failed or been cancelled
+func (s *JobStatus) Done() bool {
+	switch s.State {
+	case "COMPLETED", "FAILED", "CANCELED":
+		return true
+	}
+	return false
+}
+
+// Submit submits the query as a job and returns without waiting for it to run.
+// Only the REST transport has jobs, so it returns an error for the grpc schemes
+func (c *Connector) Submit(ctx context.Context, sql string, args ...interface{}) (*Job, error) {
+	named, err := convertArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := c.restConnection(ctx, "jobs")
+	if err != nil {
+		return nil, err
+	}
+	q := conn.newQuery(ctx, sql, named)
+	buf, err := q.buildNamed(named)
+	if err != nil {
+		return nil, err
+	}
+	info := &QueryInfo{
+		SQL:      q.Query,
+		Args:     q.args,
+		Context:  q.Context,
+		Endpoint: conn.endpoint(),
+		Started:  time.Now(),
+	}
+	ctx = conn.observer.QuerySubmitted(ctx, info)
+	release, err := conn.acquireJob(ctx, info)
+	if err != nil {
+		conn.observer.QueryFailed(ctx, info, QueryFailedEvent{Err: err, Duration: time.Since(info.Started)})
+		return nil, err
+	}
+	started := time.Now()
+	job, err := q.submit(ctx, conn, buf)
+	release()
+	if err != nil {
+		conn.observer.QueryFailed(ctx, info, QueryFailedEvent{Err: err, Duration: time.Since(info.Started)})
+		return nil, err
+	}
+	conn.observer.JobCreated(ctx, info, JobCreatedEvent{JobID: job.ID, Attempt: 1, Duration: time.Since(started)})
+	conn.log.Debug("submitted job", "job", job.ID)
+	return &Job{ID: job.ID, conn: conn, info: info}, nil
+}
+
+// Attach returns the job with the id, which may still be running or have completed.
+// The job isn't checked until one of its methods is called and doesn't take a slot
+// of Config.MaxConcurrentJobs
+func (c *Connector) Attach(ctx context.Context, jobID string) (*Job, error) {
+	if jobID == "" {
+		return nil, fmt.Errorf("missing job id")
+	}
+	conn, err := c.restConnection(ctx, "jobs")
+	if err != nil {
+		return nil, err
+	}
+	info := &QueryInfo{Endpoint: conn.endpoint(), Started: time.Now()}
+	return &Job{ID: jobID, conn: conn, info: info}, nil
+}
+
+// restConnection returns the connection for the features which only the REST API has. it's
+// opened, and logged in, on first use and shared by all of them
+func (c *Connector) restConnection(ctx context.Context, feature string) (*connection, error) {
+	if isFlight(c.cfg.Proto) {
+		return nil, fmt.Errorf("%s aren't supported with the %s scheme", feature, c.cfg.Proto)
+	}
+	c.restMu.Lock()
+	defer c.restMu.Unlock()
+	if c.rest == nil {
+		conn, err := c.Connect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.rest = conn.(*connection)
+	}
+	return c.rest, nil
+}
+
+// finish tells the observer the query is done, the first time the job is seen done
+func (j *Job) finish(ctx context.Context, status *JobStatus, err error) {
+	j.done.Do(func() {
+		if err != nil {
+			j.conn.observer.QueryFailed(ctx, j.info, QueryFailedEvent{JobID: j.ID, Err: err, Duration: time.Since(j.info.Started)})
+			return
+		}
+		j.conn.observer.QueryCompleted(ctx, j.info, QueryCompletedEvent{JobID: j.ID, Rows: status.RowCount, Duration: time.Since(j.info.Started)})
+	})
+}
+
+// Status returns the current state of the job
+func (j *Job) Status(ctx context.Context) (*JobStatus, error) {
+	resp, err := j.conn.get(ctx, j.conn.getResultStatusURL(j.ID))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var state jobState
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return nil, fmt.Errorf("error decoding JSON response. %v", err)
+	}
+	status := newJobStatus(j.ID, &state)
+	switch status.State {
+	case "COMPLETED":
+		j.finish(ctx, status, nil)
+	case "FAILED":
+		j.finish(ctx, status, newJobError(j.ID, status.ErrorMessage))
+	case "CANCELED":
+		j.finish(ctx, status, fmt.Errorf("query cancelled, job: %v", j.ID))
+	}
+	return status, nil
+}
+
+func newJobStatus(id string, state *jobState) *JobStatus {
+	status := &JobStatus{
+		ID:        id,
+		State:     state.State,
+		RowCount:  state.RowCount,
+		StartedAt: state.StartedAt,
+		EndedAt:   state.EndedAt,
+	}
+	if state.ErrorMessage != nil {
+		status.ErrorMessage = *state.ErrorMessage
+	}
+	return status
+}
+
+// Wait blocks until the job completes. It returns a *JobError if the job failed
+// and an error if it was cancelled
+func (j *Job) Wait(ctx context.Context) (*JobStatus, error) {
+	state, err := waitForJob(ctx, j.conn, j.ID, j.info)
+	if err != nil {
+		if state != nil {
+			j.finish(ctx, nil, err)
+		}
+		return nil, err
+	}
+	status := newJobStatus(j.ID, state)
+	j.finish(ctx, status, nil)
+	return status, nil
+}
+
+// Cancel asks Dremio to cancel the job
+func (j *Job) Cancel(ctx context.Context) error {
+	resp, err := j.conn.send(ctx, http.MethodPost, j.conn.getJobCancelURL(j.ID), nil, true)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	j.conn.log.Info("cancelled job", "job", j.ID)
+	j.finish(ctx, nil, fmt.Errorf("query cancelled, job: %v", j.ID))
+	return nil
+}
+
+// Rows waits for the job to complete and returns its results, fetching the pages as they are read.
+// The values are the same as the ones database/sql scans from the driver. Rows can be called
+// again to read the results from the start
+func (j *Job) Rows(ctx context.Context) (driver.Rows, error) {
+	if _, err := j.Wait(ctx); err != nil {
+		return nil, err
+	}
+	r, err := openResult(ctx, j.conn, j.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,123 @@
+package driver
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJob(t *testing.T) {
+	assert := assert.New(t)
+	var polls, cancels int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiv2/login":
+			io.WriteString(w, `{"token":"abc"}`)
+		case "/api/v3/sql":
+			io.WriteString(w, `{"id":"1"}`)
+		case "/api/v3/job/1":
+			if atomic.AddInt32(&polls, 1) == 1 {
+				io.WriteString(w, `{"jobState":"RUNNING"}`)
+				return
+			}
+			io.WriteString(w, `{"jobState":"COMPLETED","rowCount":2}`)
+		case "/api/v3/job/1/results":
+			io.WriteString(w, `{"rowCount":2,"schema":[{"name":"a"}],"rows":[{"a":1},{"a":2}]}`)
+		case "/api/v3/job/2":
+			io.WriteString(w, `{"jobState":"FAILED","errorMessage":"VALIDATION ERROR: no table"}`)
+		case "/api/v3/job/1/cancel":
+			atomic.AddInt32(&cancels, 1)
+		}
+	}))
+	defer srv.Close()
+	c := newTestConnector(t, srv)
+	ctx := context.Background()
+
+	job, err := c.Submit(ctx, "SELECT a FROM foo WHERE a > ?", 0)
+	assert.NoError(err)
+	assert.Equal("1", job.ID)
+	status, err := job.Status(ctx)
+	assert.NoError(err)
+	assert.Equal("RUNNING", status.State)
+	assert.False(status.Done())
+
+	// a different connector, as if after a restart
+	resumed := newTestConnector(t, srv)
+	job, err = resumed.Attach(ctx, "1")
+	assert.NoError(err)
+	status, err = job.Wait(ctx)
+	assert.NoError(err)
+	assert.True(status.Done())
+	assert.Equal(2, status.RowCount)
+	rows, err := job.Rows(ctx)
+	assert.NoError(err)
+	dest := make([]driver.Value, 1)
+	var vals []driver.Value
+	for rows.Next(dest) == nil {
+		vals = append(vals, dest[0])
+	}
+	assert.NoError(rows.Close())
+	assert.Equal([]driver.Value{float64(1), float64(2)}, vals)
+	assert.NoError(job.Cancel(ctx))
+	assert.Equal(int32(1), atomic.LoadInt32(&cancels))
+
+	job, err = resumed.Attach(ctx, "2")
+	assert.NoError(err)
+	_, err = job.Rows(ctx)
+	var jerr *JobError
+	assert.True(errors.As(err, &jerr))
+	assert.Equal("VALIDATION", jerr.Category)
+}
+
+func TestJobLimitAndObserver(t *testing.T) {
+	assert := assert.New(t)
+	var logins int32
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiv2/login":
+			atomic.AddInt32(&logins, 1)
+			io.WriteString(w, `{"token":"abc"}`)
+		case "/api/v3/sql":
+			io.WriteString(w, `{"id":"1"}`)
+		case "/api/v3/job/1":
+			io.WriteString(w, `{"jobState":"COMPLETED","rowCount":2}`)
+		default:
+			paths = append(paths, r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	c := newTestConnector(t, srv)
+	c.jobs = newJobLimiter(1)
+	observer := &testObserver{}
+	c.cfg.Observer = observer
+	ctx := context.Background()
+
+	job, err := c.Submit(ctx, "SELECT a FROM foo")
+	assert.NoError(err)
+	// the submitted job gave the only slot back, so a job left running doesn't hold back others
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = c.Submit(timeout, "SELECT b FROM foo")
+	assert.NoError(err)
+	_, err = job.Wait(ctx)
+	assert.NoError(err)
+	assert.Equal([]string{"submitted SELECT a FROM foo", "created 1", "submitted SELECT b FROM foo", "created 1", "state  -> COMPLETED", "completed 2"}, observer.events)
+	assert.Equal(int32(1), atomic.LoadInt32(&logins))
+
+	// attached jobs don't take a slot and their id is escaped
+	job, err = c.Attach(timeout, "a/b")
+	assert.NoError(err)
+	_, err = job.Status(ctx)
+	assert.Error(err)
+	assert.Equal([]string{"/api/v3/job/a%2Fb"}, paths)
+}
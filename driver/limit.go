@@ -0,0 +1,65 @@
+package driver
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// jobLimiter limits the number of jobs a connector runs at the same time, independently
+// of the number of connections. queries over the limit wait in line for a running job to finish
+type jobLimiter struct {
+	slots  chan struct{}
+	queued int64
+}
+
+func newJobLimiter(n int) *jobLimiter {
+	return &jobLimiter{slots: make(chan struct{}, n)}
+}
+
+// acquire waits for a slot, returning how long it waited and how many other queries were waiting
+func (l *jobLimiter) acquire(ctx context.Context) (time.Duration, int, error) {
+	select {
+	case l.slots <- struct{}{}:
+		return 0, 0, nil
+	default:
+	}
+	started := time.Now()
+	queued := int(atomic.AddInt64(&l.queued, 1)) - 1
+	defer atomic.AddInt64(&l.queued, -1)
+	select {
+	case l.slots <- struct{}{}:
+		return time.Since(started), queued, nil
+	case <-ctx.Done():
+		return time.Since(started), queued, ctx.Err()
+	}
+}
+
+func (l *jobLimiter) release() {
+	<-l.slots
+}
+
+// acquireJob waits for a job slot if the connector limits the number of running jobs,
+// the returned func must be called once the job is done
+func (c *connection) acquireJob(ctx context.Context, info *QueryInfo) (func(), error) {
+	if c.jobs == nil {
+		return func() {}, nil
+	}
+	wait, queued, err := c.jobs.acquire(ctx)
+	if o, ok := c.observer.(LimitObserver); ok {
+		o.JobSlotAcquired(ctx, info, LimitEvent{
+			Endpoint: c.endpoint(),
+			Wait:     wait,
+			Queued:   queued,
+			Running:  len(c.jobs.slots),
+			Err:      err,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if wait > 0 {
+		c.log.Debug("waited for a job slot", "wait", wait, "queued", queued)
+	}
+	return c.jobs.release, nil
+}
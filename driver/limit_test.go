@@ -0,0 +1,94 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testLimitObserver struct {
+	NopObserver
+	mu     sync.Mutex
+	events []LimitEvent
+}
+
+func (o *testLimitObserver) JobSlotAcquired(ctx context.Context, q *QueryInfo, e LimitEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, e)
+}
+
+func TestMaxConcurrentJobs(t *testing.T) {
+	assert := assert.New(t)
+	var running, most int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiv2/login":
+			io.WriteString(w, `{"token":"abc"}`)
+		case "/api/v3/sql":
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&most)
+				if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+					break
+				}
+			}
+			io.WriteString(w, `{"id":"1"}`)
+		case "/api/v3/job/1":
+			time.Sleep(50 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			io.WriteString(w, `{"jobState":"COMPLETED","rowCount":1}`)
+		case "/api/v3/job/1/results":
+			io.WriteString(w, `{"rowCount":1,"schema":[{"name":"a"}],"rows":[{"a":1}]}`)
+		}
+	}))
+	defer srv.Close()
+	c := newTestConnector(t, srv)
+	c.jobs = newJobLimiter(1)
+	o := &testLimitObserver{}
+	c.cfg.Observer = o
+	db := sql.OpenDB(c)
+	defer db.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var a int
+			assert.NoError(db.QueryRow("SELECT a FROM foo").Scan(&a))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(int32(1), atomic.LoadInt32(&most))
+	assert.Len(o.events, 4)
+	var waited int
+	for _, e := range o.events {
+		if e.Wait > 0 {
+			waited++
+		}
+	}
+	assert.True(waited > 0)
+}
+
+func TestJobLimiterCancel(t *testing.T) {
+	assert := assert.New(t)
+	l := newJobLimiter(1)
+	_, _, err := l.acquire(context.Background())
+	assert.NoError(err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, _, err = l.acquire(ctx)
+	assert.Equal(context.DeadlineExceeded, err)
+	l.release()
+	_, _, err = l.acquire(context.Background())
+	assert.NoError(err)
+}
@@ -0,0 +1,245 @@
+package driver
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+)
+
+// QueryInfo describes a query run by the driver
+type QueryInfo struct {
+	// SQL is the query as provided by the caller, before the args are substituted
+	SQL string
+	// Args are the args provided by the caller
+	Args []driver.NamedValue
+	// Context is the path the query runs in
+	Context []string
+	// Endpoint is the host and port of the coordinator
+	Endpoint string
+	// Started is when the query was submitted
+	Started time.Time
+}
+
+// JobCreatedEvent is passed to an Observer when a job was created for a query
+type JobCreatedEvent struct {
+	JobID string
+	// Attempt is the number of the submission, greater than 1 when the query is submitted again after a failure
+	Attempt int
+	// Duration is the time it took to submit the job
+	Duration time.Duration
+}
+
+// JobStateEvent is passed to an Observer when the state of a job changes
+type JobStateEvent struct {
+	JobID string
+	// State is the new state of the job, such as RUNNING or COMPLETED
+	State string
+	// Previous is the previous state of the job, empty for the first state seen
+	Previous string
+	// Duration is the time the job spent in the previous state, or waiting for the first state
+	Duration time.Duration
+}
+
+// PageEvent is passed to an Observer when a page of results was fetched
+type PageEvent struct {
+	JobID  string
+	Offset int
+	// Rows is the number of rows in the page
+	Rows int
+	// Bytes is the size of the response body
+	Bytes int
+	// Duration is the time it took to fetch the page
+	Duration time.Duration
+}
+
+// QueryCompletedEvent is passed to an Observer when all the results of a query were read or the rows were closed
+type QueryCompletedEvent struct {
+	JobID string
+	// Rows is the number of rows read by the caller
+	Rows int
+	// Duration is the time since the query was submitted
+	Duration time.Duration
+}
+
+// QueryFailedEvent is passed to an Observer when a query fails
+type QueryFailedEvent struct {
+	// JobID is the id of the job which failed. empty if the job couldn't be created
+	JobID string
+	Err   error
+	// Duration is the time since the query was submitted
+	Duration time.Duration
+}
+
+// Observer receives the lifecycle events of every query run by the driver, such as to
+// add tracing or metrics. Embed NopObserver to only implement some of the callbacks.
+// Callbacks are called synchronously and must not block
+type Observer interface {
+	// QuerySubmitted is called before a query is submitted. the returned context is
+	// used for the requests of the query and passed to the other callbacks
+	QuerySubmitted(ctx context.Context, q *QueryInfo) context.Context
+	// JobCreated is called once a job was created for the query
+	JobCreated(ctx context.Context, q *QueryInfo, e JobCreatedEvent)
+	// JobStateChanged is called when the state of the job changes while waiting for it to complete
+	JobStateChanged(ctx context.Context, q *QueryInfo, e JobStateEvent)
+	// PageFetched is called after each page of results was fetched
+	PageFetched(ctx context.Context, q *QueryInfo, e PageEvent)
+	// QueryCompleted is called once when the query completes
+	QueryCompleted(ctx context.Context, q *QueryInfo, e QueryCompletedEvent)
+	// QueryFailed is called once when the query fails
+	QueryFailed(ctx context.Context, q *QueryInfo, e QueryFailedEvent)
+}
+
+// The kinds of retries passed in a RetryEvent
+const (
+	// RetryRequest is a request retried after a transient failure
+	RetryRequest = "request"
+	// RetryJob is a query submitted again after its job failed with a transient error
+	RetryJob = "job"
+	// RetrySchemaChange is a query submitted again after its job failed with a schema change error
+	RetrySchemaChange = "schema_change"
+)
+
+// RetryEvent is passed to a RetryObserver when something is retried
+type RetryEvent struct {
+	// Endpoint is the host and port of the coordinator
+	Endpoint string
+	// Kind is what is retried, one of RetryRequest, RetryJob or RetrySchemaChange
+	Kind string
+	// Attempt is the number of the attempt which failed
+	Attempt int
+	// Err is the error of the attempt which failed
+	Err error
+}
+
+// ReloginEvent is passed to a RetryObserver when a connection logs in again because its token was rejected
+type ReloginEvent struct {
+	// Endpoint is the host and port of the coordinator
+	Endpoint string
+	// Err is the error logging in, nil if it succeeded
+	Err error
+}
+
+// RetryObserver is an optional interface that may be implemented by an Observer
+// to be notified of retries and re-logins. these can happen outside of a query,
+// such as when a connection is opened
+type RetryObserver interface {
+	// Retried is called before something is retried
+	Retried(ctx context.Context, e RetryEvent)
+	// Relogin is called after a connection logged in again
+	Relogin(ctx context.Context, e ReloginEvent)
+}
+
+// LimitEvent is passed to a LimitObserver when a query has waited for a job slot
+type LimitEvent struct {
+	// Endpoint is the host and port of the coordinator
+	Endpoint string
+	// Wait is how long the query waited for a slot
+	Wait time.Duration
+	// Queued is the number of other queries which were waiting for a slot
+	Queued int
+	// Running is the number of jobs running, including the one of this query
+	Running int
+	// Err is the error waiting, such as when the context was cancelled. nil if a slot was acquired
+	Err error
+}
+
+// LimitObserver is an optional interface that may be implemented by an Observer to be
+// notified of the time queries wait for a job slot when Config.MaxConcurrentJobs is set
+type LimitObserver interface {
+	// JobSlotAcquired is called once a query is done waiting for a job slot
+	JobSlotAcquired(ctx context.Context, q *QueryInfo, e LimitEvent)
+}
+
+// NopObserver is an Observer which does nothing
+type NopObserver struct{}
+
+var _ Observer = NopObserver{}
+
+// QuerySubmitted returns the context as is
+func (NopObserver) QuerySubmitted(ctx context.Context, q *QueryInfo) context.Context { return ctx }
+
+// JobCreated does nothing
+func (NopObserver) JobCreated(ctx context.Context, q *QueryInfo, e JobCreatedEvent) {}
+
+// JobStateChanged does nothing
+func (NopObserver) JobStateChanged(ctx context.Context, q *QueryInfo, e JobStateEvent) {}
+
+// PageFetched does nothing
+func (NopObserver) PageFetched(ctx context.Context, q *QueryInfo, e PageEvent) {}
+
+// QueryCompleted does nothing
+func (NopObserver) QueryCompleted(ctx context.Context, q *QueryInfo, e QueryCompletedEvent) {}
+
+// QueryFailed does nothing
+func (NopObserver) QueryFailed(ctx context.Context, q *QueryInfo, e QueryFailedEvent) {}
+
+type observers []Observer
+
+var _ RetryObserver = observers(nil)
+var _ LimitObserver = observers(nil)
+
+// Observers returns an Observer which passes every event to all the observers in order
+func Observers(o ...Observer) Observer {
+	return observers(o)
+}
+
+func (o observers) QuerySubmitted(ctx context.Context, q *QueryInfo) context.Context {
+	for _, each := range o {
+		ctx = each.QuerySubmitted(ctx, q)
+	}
+	return ctx
+}
+
+func (o observers) JobCreated(ctx context.Context, q *QueryInfo, e JobCreatedEvent) {
+	for _, each := range o {
+		each.JobCreated(ctx, q, e)
+	}
+}
+
+func (o observers) JobStateChanged(ctx context.Context, q *QueryInfo, e JobStateEvent) {
+	for _, each := range o {
+		each.JobStateChanged(ctx, q, e)
+	}
+}
+
+func (o observers) PageFetched(ctx context.Context, q *QueryInfo, e PageEvent) {
+	for _, each := range o {
+		each.PageFetched(ctx, q, e)
+	}
+}
+
+func (o observers) QueryCompleted(ctx context.Context, q *QueryInfo, e QueryCompletedEvent) {
+	for _, each := range o {
+		each.QueryCompleted(ctx, q, e)
+	}
+}
+
+func (o observers) QueryFailed(ctx context.Context, q *QueryInfo, e QueryFailedEvent) {
+	for _, each := range o {
+		each.QueryFailed(ctx, q, e)
+	}
+}
+
+func (o observers) Retried(ctx context.Context, e RetryEvent) {
+	for _, each := range o {
+		if r, ok := each.(RetryObserver); ok {
+			r.Retried(ctx, e)
+		}
+	}
+}
+
+func (o observers) Relogin(ctx context.Context, e ReloginEvent) {
+	for _, each := range o {
+		if r, ok := each.(RetryObserver); ok {
+			r.Relogin(ctx, e)
+		}
+	}
+}
+
+func (o observers) JobSlotAcquired(ctx context.Context, q *QueryInfo, e LimitEvent) {
+	for _, each := range o {
+		if l, ok := each.(LimitObserver); ok {
+			l.JobSlotAcquired(ctx, q, e)
+		}
+	}
+}
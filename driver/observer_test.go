@@ -0,0 +1,79 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testObserver struct {
+	NopObserver
+	events []string
+}
+
+func (o *testObserver) QuerySubmitted(ctx context.Context, q *QueryInfo) context.Context {
+	o.events = append(o.events, "submitted "+q.SQL)
+	return ctx
+}
+
+func (o *testObserver) JobCreated(ctx context.Context, q *QueryInfo, e JobCreatedEvent) {
+	o.events = append(o.events, "created "+e.JobID)
+}
+
+func (o *testObserver) JobStateChanged(ctx context.Context, q *QueryInfo, e JobStateEvent) {
+	o.events = append(o.events, "state "+e.Previous+" -> "+e.State)
+}
+
+func (o *testObserver) PageFetched(ctx context.Context, q *QueryInfo, e PageEvent) {
+	o.events = append(o.events, "page "+string(rune('0'+e.Rows)))
+}
+
+func (o *testObserver) QueryCompleted(ctx context.Context, q *QueryInfo, e QueryCompletedEvent) {
+	o.events = append(o.events, "completed "+string(rune('0'+e.Rows)))
+}
+
+func (o *testObserver) QueryFailed(ctx context.Context, q *QueryInfo, e QueryFailedEvent) {
+	o.events = append(o.events, "failed "+e.Err.Error())
+}
+
+func TestObserverEvents(t *testing.T) {
+	assert := assert.New(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiv2/login":
+			io.WriteString(w, `{"token":"abc"}`)
+		case "/api/v3/sql":
+			io.WriteString(w, `{"id":"1"}`)
+		case "/api/v3/job/1":
+			io.WriteString(w, `{"jobState":"COMPLETED","rowCount":2}`)
+		case "/api/v3/job/1/results":
+			io.WriteString(w, `{"rowCount":2,"schema":[{"name":"a"}],"rows":[{"a":"x"},{"a":"y"}]}`)
+		}
+	}))
+	defer srv.Close()
+	c := newTestConnector(t, srv)
+	o := &testObserver{}
+	c.cfg.Observer = o
+	db := sql.OpenDB(c)
+	defer db.Close()
+	rows, err := db.Query("SELECT a FROM foo WHERE b = ?", "c")
+	assert.NoError(err)
+	var count int
+	for rows.Next() {
+		count++
+	}
+	rows.Close()
+	assert.Equal(2, count)
+	assert.Equal([]string{
+		"submitted SELECT a FROM foo WHERE b = ?",
+		"created 1",
+		"state  -> COMPLETED",
+		"page 2",
+		"completed 2",
+	}, o.events)
+}
@@ -0,0 +1,60 @@
+package driver
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+)
+
+type contextPathKey struct{}
+type pageSizeKey struct{}
+type engineKey struct{}
+
+// WithContextPath returns a context which makes queries run in the path instead of
+// the context of the connection, such as WithContextPath(ctx, "Samples", "samples.dremio.com").
+// an empty path runs queries without a context
+func WithContextPath(ctx context.Context, path ...string) context.Context {
+	return context.WithValue(ctx, contextPathKey{}, path)
+}
+
+// WithPageSize returns a context which makes queries fetch their results in pages of n
+// rows instead of the page size of the connection. must be between 1-500
+func WithPageSize(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, pageSizeKey{}, n)
+}
+
+// WithEngine returns a context which makes queries run on the engine instead of the
+// engine of the connection. only supported with Dremio Cloud
+func WithEngine(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, engineKey{}, name)
+}
+
+// newQuery returns the query using the options of the context over the ones of the connection
+func (c *connection) newQuery(ctx context.Context, sql string, args []driver.NamedValue) query {
+	q := query{Query: sql, Context: c.context, References: c.references(ctx), args: args}
+	if path, ok := ctx.Value(contextPathKey{}).([]string); ok {
+		q.Context = path
+	}
+	if c.project != "" {
+		q.Engine = c.engine
+		if engine, ok := ctx.Value(engineKey{}).(string); ok {
+			q.Engine = engine
+		}
+	}
+	return q
+}
+
+// pageSize returns the page size of the context, or the one of the connection
+func (c *connection) pageSize(ctx context.Context) int {
+	if n, ok := ctx.Value(pageSizeKey{}).(int); ok && n > 0 && n <= 500 {
+		return n
+	}
+	return c.pagesize
+}
+
+func checkOptions(ctx context.Context) error {
+	if n, ok := ctx.Value(pageSizeKey{}).(int); ok && (n <= 0 || n > 500) {
+		return fmt.Errorf("invalid page size. must be between 1-500")
+	}
+	return nil
+}
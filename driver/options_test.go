@@ -0,0 +1,51 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryOptions(t *testing.T) {
+	assert := assert.New(t)
+	var contexts [][]string
+	var limits []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiv2/login":
+			io.WriteString(w, `{"token":"abc"}`)
+		case "/api/v3/sql":
+			var q query
+			json.NewDecoder(r.Body).Decode(&q)
+			contexts = append(contexts, q.Context)
+			io.WriteString(w, `{"id":"1"}`)
+		case "/api/v3/job/1":
+			io.WriteString(w, `{"jobState":"COMPLETED","rowCount":1}`)
+		case "/api/v3/job/1/results":
+			limits = append(limits, r.URL.Query().Get("limit"))
+			io.WriteString(w, `{"rowCount":1,"schema":[{"name":"a"}],"rows":[{"a":1}]}`)
+		}
+	}))
+	defer srv.Close()
+	c := newTestConnector(t, srv)
+	c.cfg.Context = []string{"Default"}
+	db := sql.OpenDB(c)
+	defer db.Close()
+	var a int
+	ctx := context.Background()
+	assert.NoError(db.QueryRowContext(ctx, "SELECT a FROM foo").Scan(&a))
+	ctx = WithPageSize(WithContextPath(ctx, "Samples", "samples.dremio.com"), 10)
+	assert.NoError(db.QueryRowContext(ctx, "SELECT a FROM foo").Scan(&a))
+	assert.NoError(db.QueryRowContext(WithContextPath(context.Background()), "SELECT a FROM foo").Scan(&a))
+	assert.Equal([][]string{{"Default"}, {"Samples", "samples.dremio.com"}, nil}, contexts)
+	assert.Equal([]string{"500", "10", "500"}, limits)
+
+	_, err := db.QueryContext(WithPageSize(context.Background(), 501), "SELECT a FROM foo")
+	assert.EqualError(err, "invalid page size. must be between 1-500")
+}
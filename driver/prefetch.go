@@ -0,0 +1,84 @@
+package driver
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type prefetchKey struct{}
+
+// WithPrefetch returns a context which makes queries fetch the next page of results
+// in the background while the current one is read. only one page is fetched ahead
+func WithPrefetch(ctx context.Context) context.Context {
+	return context.WithValue(ctx, prefetchKey{}, true)
+}
+
+func prefetchEnabled(ctx context.Context) bool {
+	prefetch, _ := ctx.Value(prefetchKey{}).(bool)
+	return prefetch
+}
+
+// prefetched is a page fetched ahead of time
+type prefetched struct {
+	page []map[string]interface{}
+	err  error
+}
+
+// prefetchNext starts fetching the page after the current one, if there is one
+func (r *result) prefetchNext() {
+	if !r.prefetch || r.shared != nil || r.next != nil || r.offset >= r.total {
+		return
+	}
+	ctx, cancel := context.WithCancel(r.ctx)
+	next := make(chan prefetched, 1)
+	r.next, r.stop = next, cancel
+	tmp := &result{ctx: ctx, conn: r.conn, jobid: r.jobid, info: r.info, columns: r.columns, offset: r.offset}
+	total := r.total
+	go func() {
+		if err := fetchNextPage(ctx, tmp.conn, tmp.jobid, tmp.offset, total, tmp); err != nil {
+			next <- prefetched{err: err}
+			return
+		}
+		next <- prefetched{page: tmp.rows.rows}
+	}()
+}
+
+// takePrefetched waits for the prefetched page and makes it the current one
+func (r *result) takePrefetched() error {
+	p := <-r.next
+	r.next = nil
+	r.stop()
+	if p.err != nil {
+		return p.err
+	}
+	r.offset += len(p.page)
+	r.rows = &rows{parent: r, rows: p.page}
+	r.record(p.page)
+	r.prefetchNext()
+	return nil
+}
+
+// stopPrefetch cancels the page being prefetched and waits for it, so that
+// the connection isn't used once the rows are closed
+func (r *result) stopPrefetch() {
+	if r.next != nil {
+		r.stop()
+		<-r.next
+		r.next = nil
+	}
+}
+
+// cancelJob asks Dremio to cancel a job which is no longer wanted, such as
+// when the context of its query was cancelled while it was running
+func (c *connection) cancelJob(jobid string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := c.send(ctx, http.MethodPost, c.getJobCancelURL(jobid), nil, true)
+	if err != nil {
+		c.log.Warn("error cancelling job", "job", jobid, "err", err)
+		return
+	}
+	resp.Body.Close()
+	c.log.Info("cancelled job", "job", jobid)
+}
@@ -0,0 +1,98 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefetch(t *testing.T) {
+	assert := assert.New(t)
+	var fetches int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiv2/login":
+			io.WriteString(w, `{"token":"abc"}`)
+		case "/api/v3/sql":
+			io.WriteString(w, `{"id":"1"}`)
+		case "/api/v3/job/1":
+			io.WriteString(w, `{"jobState":"COMPLETED","rowCount":5}`)
+		case "/api/v3/job/1/results":
+			atomic.AddInt32(&fetches, 1)
+			switch r.URL.Query().Get("offset") {
+			case "0":
+				io.WriteString(w, `{"rowCount":5,"schema":[{"name":"a"}],"rows":[{"a":1},{"a":2}]}`)
+			case "2":
+				io.WriteString(w, `{"rowCount":5,"schema":[{"name":"a"}],"rows":[{"a":3},{"a":4}]}`)
+			case "4":
+				io.WriteString(w, `{"rowCount":5,"schema":[{"name":"a"}],"rows":[{"a":5}]}`)
+			}
+		}
+	}))
+	defer srv.Close()
+	c := newTestConnector(t, srv)
+	c.cfg.PageSize = 2
+	db := sql.OpenDB(c)
+	defer db.Close()
+
+	rows, err := db.QueryContext(WithPrefetch(context.Background()), "SELECT a FROM foo")
+	assert.NoError(err)
+	var vals []int
+	for rows.Next() {
+		var a int
+		assert.NoError(rows.Scan(&a))
+		vals = append(vals, a)
+	}
+	assert.NoError(rows.Err())
+	assert.NoError(rows.Close())
+	assert.Equal([]int{1, 2, 3, 4, 5}, vals)
+	// each page is fetched once
+	assert.Equal(int32(3), atomic.LoadInt32(&fetches))
+
+	// closing early cancels the page being prefetched and waits for it
+	atomic.StoreInt32(&fetches, 0)
+	rows, err = db.QueryContext(WithPrefetch(context.Background()), "SELECT a FROM foo")
+	assert.NoError(err)
+	assert.True(rows.Next())
+	assert.NoError(rows.Close())
+	fetched := atomic.LoadInt32(&fetches)
+	assert.True(fetched == 1 || fetched == 2)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(fetched, atomic.LoadInt32(&fetches))
+}
+
+func TestCancelRunningJob(t *testing.T) {
+	assert := assert.New(t)
+	cancelled := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiv2/login":
+			io.WriteString(w, `{"token":"abc"}`)
+		case "/api/v3/sql":
+			io.WriteString(w, `{"id":"1"}`)
+		case "/api/v3/job/1":
+			io.WriteString(w, `{"jobState":"RUNNING"}`)
+		case "/api/v3/job/1/cancel":
+			close(cancelled)
+		}
+	}))
+	defer srv.Close()
+	db := sql.OpenDB(newTestConnector(t, srv))
+	defer db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := db.QueryContext(ctx, "SELECT a FROM foo")
+	assert.Error(err)
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		assert.Fail("the job wasn't cancelled")
+	}
+}
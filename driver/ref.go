@@ -0,0 +1,113 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// The types of version references
+const (
+	RefBranch = "BRANCH"
+	RefTag    = "TAG"
+	RefCommit = "COMMIT"
+)
+
+// Ref is a version reference of a versioned source, such as a branch of a Nessie or Arctic catalog
+type Ref struct {
+	// Type is one of RefBranch, RefTag or RefCommit
+	Type string `json:"type"`
+	// Value is the name of the branch or tag, or the hash of the commit
+	Value string `json:"value"`
+}
+
+// Branch returns the reference of a branch
+func Branch(name string) Ref {
+	return Ref{Type: RefBranch, Value: name}
+}
+
+// Tag returns the reference of a tag
+func Tag(name string) Ref {
+	return Ref{Type: RefTag, Value: name}
+}
+
+// Commit returns the reference of a commit
+func Commit(hash string) Ref {
+	return Ref{Type: RefCommit, Value: hash}
+}
+
+func (r Ref) String() string {
+	return strings.ToLower(r.Type) + ":" + r.Value
+}
+
+// ParseRef parses a reference such as branch:dev, tag:v1 or commit:abc123
+func ParseRef(s string) (Ref, error) {
+	i := strings.Index(s, ":")
+	if i <= 0 || i == len(s)-1 {
+		return Ref{}, fmt.Errorf("invalid reference %q. must be branch:name, tag:name or commit:hash", s)
+	}
+	ref := Ref{Type: strings.ToUpper(s[:i]), Value: s[i+1:]}
+	switch ref.Type {
+	case RefBranch, RefTag, RefCommit:
+		return ref, nil
+	}
+	return Ref{}, fmt.Errorf("invalid reference type %q. must be branch, tag or commit", s[:i])
+}
+
+// parseRefs parses the ref values of a DSN. each is a reference optionally prefixed with the
+// name of its source, such as nessie:branch:dev. a reference without a source is for the
+// source at the root of the context path
+func parseRefs(values []string, context []string) (map[string]Ref, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	refs := make(map[string]Ref)
+	for _, v := range values {
+		source := ""
+		if strings.Count(v, ":") > 1 {
+			i := strings.Index(v, ":")
+			source, v = v[:i], v[i+1:]
+		} else if len(context) > 0 {
+			source = context[0]
+		} else {
+			return nil, fmt.Errorf("invalid ref %q. must have a source, such as source:%s, when there is no context", v, v)
+		}
+		ref, err := ParseRef(v)
+		if err != nil {
+			return nil, err
+		}
+		refs[source] = ref
+	}
+	return refs, nil
+}
+
+type refsKey struct{}
+
+// WithRef returns a context which makes queries read the source at the reference,
+// over the reference of the connection, such as WithRef(ctx, "nessie", Branch("dev"))
+func WithRef(ctx context.Context, source string, ref Ref) context.Context {
+	refs := make(map[string]Ref)
+	if parent, ok := ctx.Value(refsKey{}).(map[string]Ref); ok {
+		for k, v := range parent {
+			refs[k] = v
+		}
+	}
+	refs[source] = ref
+	return context.WithValue(ctx, refsKey{}, refs)
+}
+
+// references returns the references of the connection with the ones of the context over them
+func (c *connection) references(ctx context.Context) map[string]Ref {
+	override, _ := ctx.Value(refsKey{}).(map[string]Ref)
+	if len(override) == 0 {
+		return c.refs
+	}
+	refs := make(map[string]Ref, len(c.refs)+len(override))
+	for k, v := range c.refs {
+		refs[k] = v
+	}
+	for k, v := range override {
+		refs[k] = v
+	}
+	return refs
+}
@@ -0,0 +1,208 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// The types of reflections
+const (
+	ReflectionRaw         = "RAW"
+	ReflectionAggregation = "AGGREGATION"
+)
+
+// ErrReflectionConflict is returned when a reflection is updated with a tag which isn't its
+// current one, because it was changed since it was read
+var ErrReflectionConflict = errors.New("reflection was changed since it was read")
+
+// Reflection is a raw or aggregation reflection of a dataset
+type Reflection struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"`
+	// Type is one of the Reflection constants
+	Type      string `json:"type"`
+	DatasetID string `json:"datasetId"`
+	// Tag is the version of the reflection, which must be sent back to update it
+	Tag                 string `json:"tag,omitempty"`
+	Enabled             bool   `json:"enabled"`
+	ArrowCachingEnabled bool   `json:"arrowCachingEnabled,omitempty"`
+	// DisplayFields are the fields of a raw reflection
+	DisplayFields []ReflectionField `json:"displayFields,omitempty"`
+	// DimensionFields and MeasureFields are the fields of an aggregation reflection
+	DimensionFields               []DimensionField  `json:"dimensionFields,omitempty"`
+	MeasureFields                 []MeasureField    `json:"measureFields,omitempty"`
+	PartitionFields               []ReflectionField `json:"partitionFields,omitempty"`
+	SortFields                    []ReflectionField `json:"sortFields,omitempty"`
+	DistributionFields            []ReflectionField `json:"distributionFields,omitempty"`
+	PartitionDistributionStrategy string            `json:"partitionDistributionStrategy,omitempty"`
+	Status                        *ReflectionStatus `json:"status,omitempty"`
+	CurrentSizeBytes              int64             `json:"currentSizeBytes,omitempty"`
+	TotalSizeBytes                int64             `json:"totalSizeBytes,omitempty"`
+	CreatedAt                     *time.Time        `json:"createdAt,omitempty"`
+	UpdatedAt                     *time.Time        `json:"updatedAt,omitempty"`
+}
+
+// ReflectionField is a field of a reflection
+type ReflectionField struct {
+	Name string `json:"name"`
+}
+
+// DimensionField is a dimension of an aggregation reflection
+type DimensionField struct {
+	Name string `json:"name"`
+	// Granularity is NORMAL or DATE for a timestamp truncated to its date
+	Granularity string `json:"granularity,omitempty"`
+}
+
+// MeasureField is a measure of an aggregation reflection
+type MeasureField struct {
+	Name string `json:"name"`
+	// MeasureTypeList are the aggregations of the measure, such as SUM, COUNT, MIN, MAX or APPROX_COUNT_DISTINCT
+	MeasureTypeList []string `json:"measureTypeList,omitempty"`
+}
+
+// ReflectionStatus is the state of a reflection
+type ReflectionStatus struct {
+	// Config is OK or INVALID
+	Config string `json:"config"`
+	// Refresh is SCHEDULED, RUNNING, GIVEN_UP or MANUAL
+	Refresh string `json:"refresh"`
+	// Availability is NONE, INCOMPLETE, EXPIRED or AVAILABLE
+	Availability string `json:"availability"`
+	// CombinedStatus sums up the others, such as CAN_ACCELERATE or REFRESHING
+	CombinedStatus string `json:"combinedStatus"`
+	FailureCount   int    `json:"failureCount"`
+	// LastDataFetch is when the data of the last refresh was read from its sources
+	LastDataFetch *time.Time `json:"lastDataFetch,omitempty"`
+	ExpiresAt     *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Reflections is a client for the reflection REST API, sharing the configuration and authentication of the connector
+type Reflections struct {
+	conn *connection
+}
+
+// Reflections returns a client for the reflections. Only the REST API has the reflections, so it returns
+// an error for the grpc schemes
+func (c *Connector) Reflections(ctx context.Context) (*Reflections, error) {
+	conn, err := c.restConnection(ctx, "reflections")
+	if err != nil {
+		return nil, err
+	}
+	return &Reflections{conn: conn}, nil
+}
+
+func (c *connection) getReflectionURL(id string) string {
+	if id == "" {
+		return c.getAPIURL("/reflection")
+	}
+	return c.getAPIURL("/reflection/" + url.PathEscape(id))
+}
+
+func (r *Reflections) list(ctx context.Context, u string) ([]Reflection, error) {
+	var res struct {
+		Data []Reflection `json:"data"`
+	}
+	if err := r.conn.doJSON(ctx, http.MethodGet, u, nil, &res); err != nil {
+		return nil, err
+	}
+	return res.Data, nil
+}
+
+// List returns all the reflections
+func (r *Reflections) List(ctx context.Context) ([]Reflection, error) {
+	return r.list(ctx, r.conn.getReflectionURL(""))
+}
+
+// ListForDataset returns the reflections of the dataset with the id
+func (r *Reflections) ListForDataset(ctx context.Context, datasetID string) ([]Reflection, error) {
+	if datasetID == "" {
+		return nil, fmt.Errorf("missing dataset id")
+	}
+	return r.list(ctx, r.conn.getAPIURL("/dataset/"+url.PathEscape(datasetID)+"/reflection"))
+}
+
+// Get returns the reflection with the id
+func (r *Reflections) Get(ctx context.Context, id string) (*Reflection, error) {
+	if id == "" {
+		return nil, fmt.Errorf("missing reflection id")
+	}
+	var reflection Reflection
+	if err := r.conn.doJSON(ctx, http.MethodGet, r.conn.getReflectionURL(id), nil, &reflection); err != nil {
+		return nil, err
+	}
+	return &reflection, nil
+}
+
+// Status returns the state of the reflection with the id, such as whether it is refreshing
+// and when its data was last refreshed
+func (r *Reflections) Status(ctx context.Context, id string) (*ReflectionStatus, error) {
+	reflection, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if reflection.Status == nil {
+		return nil, fmt.Errorf("missing status of reflection %s", id)
+	}
+	return reflection.Status, nil
+}
+
+// Create creates the reflection and returns it as created
+func (r *Reflections) Create(ctx context.Context, reflection *Reflection) (*Reflection, error) {
+	body := struct {
+		EntityType string `json:"entityType"`
+		*Reflection
+	}{"reflection", reflection}
+	var created Reflection
+	if err := r.conn.doJSON(ctx, http.MethodPost, r.conn.getReflectionURL(""), body, &created); err != nil {
+		return nil, err
+	}
+	r.conn.log.Info("created reflection", "id", created.ID, "dataset", reflection.DatasetID, "type", reflection.Type)
+	return &created, nil
+}
+
+// Update replaces the reflection and returns it with its new tag. The tag of the reflection must
+// be the current one, else an error wrapping ErrReflectionConflict is returned and the reflection
+// should be read again before retrying
+func (r *Reflections) Update(ctx context.Context, reflection *Reflection) (*Reflection, error) {
+	if reflection.ID == "" {
+		return nil, fmt.Errorf("missing reflection id")
+	}
+	var updated Reflection
+	if err := r.conn.doJSON(ctx, http.MethodPut, r.conn.getReflectionURL(reflection.ID), reflection, &updated); err != nil {
+		return nil, conflictError(err, ErrReflectionConflict)
+	}
+	r.conn.log.Info("updated reflection", "id", reflection.ID)
+	return &updated, nil
+}
+
+// SetEnabled enables or disables the reflection with the id and returns it as updated
+func (r *Reflections) SetEnabled(ctx context.Context, id string, enabled bool) (*Reflection, error) {
+	reflection, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if reflection.Enabled == enabled {
+		return reflection, nil
+	}
+	reflection.Enabled = enabled
+	// the status is computed by dremio
+	reflection.Status = nil
+	return r.Update(ctx, reflection)
+}
+
+// Delete deletes the reflection with the id
+func (r *Reflections) Delete(ctx context.Context, id string) error {
+	if id == "" {
+		return fmt.Errorf("missing reflection id")
+	}
+	if err := r.conn.doJSON(ctx, http.MethodDelete, r.conn.getReflectionURL(id), nil, nil); err != nil {
+		return err
+	}
+	r.conn.log.Info("deleted reflection", "id", id)
+	return nil
+}
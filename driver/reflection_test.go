@@ -0,0 +1,88 @@
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReflections(t *testing.T) {
+	assert := assert.New(t)
+	var created map[string]interface{}
+	var updated Reflection
+	var deleted bool
+	const r1 = `{"id":"r1","name":"totals","type":"AGGREGATION","datasetId":"d1","tag":"t1","enabled":true,
+		"dimensionFields":[{"name":"day","granularity":"DATE"}],"measureFields":[{"name":"total","measureTypeList":["SUM"]}],
+		"status":{"config":"OK","refresh":"SCHEDULED","availability":"AVAILABLE","combinedStatus":"CAN_ACCELERATE","lastDataFetch":"2019-06-11T19:08:27.447Z"}}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /apiv2/login":
+			io.WriteString(w, `{"token":"abc"}`)
+		case "GET /api/v3/reflection", "GET /api/v3/dataset/d1/reflection":
+			io.WriteString(w, `{"data":[`+r1+`]}`)
+		case "GET /api/v3/reflection/r1":
+			io.WriteString(w, r1)
+		case "POST /api/v3/reflection":
+			json.NewDecoder(r.Body).Decode(&created)
+			io.WriteString(w, `{"id":"r2","name":"raw","type":"RAW","datasetId":"d1","tag":"t1","enabled":true}`)
+		case "PUT /api/v3/reflection/r1":
+			json.NewDecoder(r.Body).Decode(&updated)
+			if updated.Tag != "t1" {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			updated.Tag = "t2"
+			json.NewEncoder(w).Encode(updated)
+		case "DELETE /api/v3/reflection/r2":
+			deleted = true
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	reflections, err := newTestConnector(t, srv).Reflections(ctx)
+	assert.NoError(err)
+
+	list, err := reflections.ListForDataset(ctx, "d1")
+	assert.NoError(err)
+	assert.Len(list, 1)
+	assert.Equal(ReflectionAggregation, list[0].Type)
+	assert.Equal([]string{"SUM"}, list[0].MeasureFields[0].MeasureTypeList)
+	assert.Equal("DATE", list[0].DimensionFields[0].Granularity)
+
+	status, err := reflections.Status(ctx, "r1")
+	assert.NoError(err)
+	assert.Equal("CAN_ACCELERATE", status.CombinedStatus)
+	assert.Equal(time.Date(2019, 6, 11, 19, 8, 27, 447000000, time.UTC), *status.LastDataFetch)
+
+	raw, err := reflections.Create(ctx, &Reflection{
+		Name:          "raw",
+		Type:          ReflectionRaw,
+		DatasetID:     "d1",
+		Enabled:       true,
+		DisplayFields: []ReflectionField{{Name: "day"}, {Name: "total"}},
+	})
+	assert.NoError(err)
+	assert.Equal("r2", raw.ID)
+	assert.Equal("reflection", created["entityType"])
+	assert.Len(created["displayFields"], 2)
+
+	disabled, err := reflections.SetEnabled(ctx, "r1", false)
+	assert.NoError(err)
+	assert.False(disabled.Enabled)
+	assert.Equal("t2", disabled.Tag)
+	assert.Nil(updated.Status)
+	_, err = reflections.Update(ctx, disabled)
+	assert.True(errors.Is(err, ErrReflectionConflict))
+
+	assert.NoError(reflections.Delete(ctx, "r2"))
+	assert.True(deleted)
+}
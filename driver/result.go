@@ -2,14 +2,13 @@ package driver
 
 import (
 	"bytes"
-	"database/sql"
+	"context"
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"strings"
 	"time"
 )
 
@@ -33,7 +32,14 @@ func (r *jobResults) Read(in io.Reader) error {
 }
 
 type schema struct {
-	Name string `json:"name"`
+	Name string     `json:"name"`
+	Type schemaType `json:"type"`
+}
+
+type schemaType struct {
+	Name      string `json:"name"`
+	Precision int32  `json:"precision,omitempty"`
+	Scale     int32  `json:"scale,omitempty"`
 }
 
 type jobid struct {
@@ -43,31 +49,86 @@ type jobid struct {
 type columns []string
 
 type result struct {
+	ctx     context.Context
 	jobid   string
 	conn    *connection
+	info    *QueryInfo
 	columns columns
+	schema  []schema
 	rows    *rows
 	offset  int
 	total   int
+	read    int
+	done    bool
+
+	// the cache the rows are recorded for, until the last page has been fetched
+	cache *ResultCache
+	key   string
+	pages []map[string]interface{}
+
+	// the pages shared with identical queries running at the same time
+	shared *sharedPages
+
+	// the page fetched in the background while the current one is read
+	prefetch bool
+	next     chan prefetched
+	stop     context.CancelFunc
+}
+
+// complete notifies the observer that the query completed, if it hasn't been already
+func (r *result) complete(err error) {
+	r.stopPrefetch()
+	if r.shared != nil {
+		r.shared.leave(r.offset)
+		r.shared = nil
+	}
+	if err == nil && r.cache != nil && r.offset >= r.total {
+		r.cache.put(&cacheEntry{key: r.key, columns: r.columns, schema: r.schema, rows: r.pages})
+	}
+	r.cache = nil
+	if r.done || r.info == nil {
+		return
+	}
+	r.done = true
+	if err != nil {
+		r.conn.observer.QueryFailed(r.ctx, r.info, QueryFailedEvent{JobID: r.jobid, Err: err, Duration: time.Since(r.info.Started)})
+		return
+	}
+	r.conn.observer.QueryCompleted(r.ctx, r.info, QueryCompletedEvent{JobID: r.jobid, Rows: r.read, Duration: time.Since(r.info.Started)})
 }
 
-func fetchNextPage(conn *connection, jobid string, offset int, total int, res *result) error {
-	resp, err := conn.get(conn.getResultURL(jobid, offset, conn.pagesize))
+func fetchNextPage(ctx context.Context, conn *connection, jobid string, offset int, total int, res *result) error {
+	started := time.Now()
+	resp, err := conn.get(ctx, conn.getResultURL(jobid, offset, conn.pageSize(ctx)))
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
-	var jr jobResults
 	buf, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return err
 	}
+	var jr jobResults
 	if err := jr.Read(bytes.NewReader(buf)); err != nil {
 		return err
 	}
+	return readPage(ctx, conn, jobid, offset, total, res, &jr, len(buf), started)
+}
+
+// readPage sets the rows of the result to a page of results which was fetched since started
+func readPage(ctx context.Context, conn *connection, jobid string, offset int, total int, res *result, jr *jobResults, size int, started time.Time) error {
 	if jr.Error != "" {
 		return errors.New(jr.Error)
 	}
+	if res.info != nil {
+		conn.observer.PageFetched(ctx, res.info, PageEvent{
+			JobID:    jobid,
+			Offset:   offset,
+			Rows:     len(jr.Rows),
+			Bytes:    size,
+			Duration: time.Since(started),
+		})
+	}
 	res.total = total
 	res.offset += len(jr.Rows)
 	if res.columns == nil {
@@ -75,21 +136,41 @@ func fetchNextPage(conn *connection, jobid string, offset int, total int, res *r
 		for _, e := range jr.Schema {
 			res.columns = append(res.columns, e.Name)
 		}
+		res.schema = jr.Schema
 	}
 	res.rows = &rows{
 		parent: res,
 		rows:   make([]map[string]interface{}, 0),
 	}
 	res.rows.rows = append(res.rows.rows, jr.Rows...)
+	res.record(jr.Rows)
+	return nil
+}
+
+// fetch fetches the next page of the result
+func (r *result) fetch() error {
+	if r.shared != nil {
+		return r.shared.fetch(r)
+	}
+	if r.next != nil {
+		return r.takePrefetched()
+	}
+	if err := fetchNextPage(r.ctx, r.conn, r.jobid, r.offset, r.total, r); err != nil {
+		return err
+	}
+	r.prefetchNext()
 	return nil
 }
 
-func newResult(conn *connection, jobid string, query *query) (driver.Rows, error) {
+// waitForJob polls the state of the job until it completes, returning an error if it fails or is
+// cancelled. the final state is returned along with the error of a failed or cancelled job
+func waitForJob(ctx context.Context, conn *connection, jobid string, info *QueryInfo) (*jobState, error) {
 	jobResultURL := conn.getResultStatusURL(jobid)
 	var state jobState
-end:
+	var previous string
+	changed := time.Now()
 	for {
-		resp, err := conn.get(jobResultURL)
+		resp, err := conn.get(ctx, jobResultURL)
 		if err != nil {
 			return nil, err
 		}
@@ -98,43 +179,76 @@ end:
 			return nil, fmt.Errorf("error decoding JSON response. %v", err)
 		}
 		resp.Body.Close()
-		// buf, _ := json.MarshalIndent(state, "", " ")
-		// fmt.Println(string(buf))
+		if state.State != previous {
+			if info != nil {
+				conn.observer.JobStateChanged(ctx, info, JobStateEvent{
+					JobID:    jobid,
+					State:    state.State,
+					Previous: previous,
+					Duration: time.Since(changed),
+				})
+			}
+			previous = state.State
+			changed = time.Now()
+		}
 		switch state.State {
 		case "FAILED":
 			if state.ErrorMessage == nil {
-				return nil, fmt.Errorf("unknown error running query: %v", string(query.buf))
-			}
-			if strings.Contains(*state.ErrorMessage, "SCHEMA_CHANGE ERROR") {
-				// this is a failure that needs to be restarted because the schema is in learning mode
-				time.Sleep(time.Millisecond * 20)
-				return query.send(conn, query.buf)
+				return &state, fmt.Errorf("unknown error running query, job: %v", jobid)
 			}
-			return nil, errors.New(*state.ErrorMessage)
+			return &state, newJobError(jobid, *state.ErrorMessage)
 		case "COMPLETED":
-			break end
+			return &state, nil
 		case "CANCELLATION_REQUESTED":
-			fmt.Println("query cancel requested, query:", string(query.buf))
+			conn.log.Warn("query cancel requested", "job", jobid)
 		case "CANCELED":
-			return nil, fmt.Errorf("query cancelled, query: %v", string(query.buf))
-		default:
-			time.Sleep(time.Second)
+			conn.log.Warn("query cancelled", "job", jobid)
+			return &state, fmt.Errorf("query cancelled, job: %v", jobid)
+		}
+		conn.log.Debug("waiting for job", "job", jobid, "state", state.State)
+		if err := sleep(ctx, time.Second); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func newResult(ctx context.Context, conn *connection, jobid string, info *QueryInfo) (driver.Rows, error) {
+	if _, err := waitForJob(ctx, conn, jobid, info); err != nil {
+		if ctx.Err() != nil {
+			// nobody is waiting for the job anymore
+			conn.cancelJob(jobid)
 		}
+		return nil, err
+	}
+	r, err := openResult(ctx, conn, jobid, info)
+	if err != nil {
+		return nil, err
 	}
-	resp, err := conn.get(conn.getResultURL(jobid, 0, conn.pagesize))
+	return r, nil
+}
+
+// openResult returns the rows of a completed job, starting with its first page
+func openResult(ctx context.Context, conn *connection, jobid string, info *QueryInfo) (*rows, error) {
+	started := time.Now()
+	resp, err := conn.get(ctx, conn.getResultURL(jobid, 0, conn.pageSize(ctx)))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	var jr jobResults
-	var result result
+	result := result{
+		ctx:   ctx,
+		jobid: jobid,
+		conn:  conn,
+		info:  info,
+	}
 	buf, _ := ioutil.ReadAll(resp.Body)
-	// fmt.Println(string(buf))
 	if bytes.HasPrefix(buf, []byte("{")) {
 		if err := jr.Read(bytes.NewReader(buf)); err != nil {
 			return nil, err
 		}
-		if err := fetchNextPage(conn, jobid, 0, jr.RowCount, &result); err != nil {
+		// the first page also has the number of rows of the result
+		if err := readPage(ctx, conn, jobid, 0, jr.RowCount, &result, &jr, len(buf), started); err != nil {
 			return nil, err
 		}
 	} else {
@@ -148,8 +262,8 @@ end:
 			rows:   make([]map[string]interface{}, 0),
 		}
 	}
-	result.jobid = jobid
-	result.conn = conn
+	result.prefetch = prefetchEnabled(ctx)
+	result.prefetchNext()
 	return result.rows, nil
 }
 
@@ -171,6 +285,7 @@ func (r *rows) Columns() []string {
 
 // Close closes the rows iterator.
 func (r *rows) Close() error {
+	r.parent.complete(nil)
 	return nil
 }
 
@@ -186,7 +301,8 @@ func (r *rows) Close() error {
 func (r *rows) Next(dest []driver.Value) error {
 	if r.index >= len(r.rows) {
 		if r.parent.offset < r.parent.total {
-			if err := fetchNextPage(r.parent.conn, r.parent.jobid, r.parent.offset, r.parent.total, r.parent); err != nil {
+			if err := r.parent.fetch(); err != nil {
+				r.parent.complete(err)
 				return err
 			}
 			r.index = r.parent.rows.index
@@ -199,7 +315,8 @@ func (r *rows) Next(dest []driver.Value) error {
 		return fmt.Errorf("invalid scan, expected %d arguments and received %d", len(r.parent.columns), len(dest))
 	}
 	if r.rows == nil || len(r.rows) == 0 {
-		return sql.ErrNoRows
+		r.parent.complete(nil)
+		return io.EOF
 	}
 	therow := r.rows[r.index]
 	for i := 0; i < len(r.parent.columns); i++ {
@@ -208,5 +325,31 @@ func (r *rows) Next(dest []driver.Value) error {
 		dest[i] = val
 	}
 	r.index++
+	r.parent.read++
 	return nil
 }
+
+// nextPage returns the unread rows of the current page and moves to the next page.
+// it returns nil when there are no more rows
+func (r *rows) nextPage() ([]map[string]interface{}, error) {
+	if r.index >= len(r.rows) {
+		if r.parent.offset >= r.parent.total {
+			r.parent.complete(nil)
+			return nil, nil
+		}
+		if err := r.parent.fetch(); err != nil {
+			r.parent.complete(err)
+			return nil, err
+		}
+		r.index = r.parent.rows.index
+		r.rows = r.parent.rows.rows
+		if len(r.rows) == 0 {
+			r.parent.complete(nil)
+			return nil, nil
+		}
+	}
+	page := r.rows[r.index:]
+	r.index = len(r.rows)
+	r.parent.read += len(page)
+	return page, nil
+}
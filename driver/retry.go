@@ -0,0 +1,188 @@
+package driver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// RetryPolicy controls how requests which fail because of transient
+// coordinator failures are retried. It applies to job submission, job
+// status polling and fetching result pages
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first one. 1 disables retries
+	MaxAttempts int
+	// Backoff is the time to wait before the first retry, doubled on every retry after that
+	Backoff time.Duration
+	// MaxBackoff is the maximum time to wait between retries
+	MaxBackoff time.Duration
+	// Statuses are the HTTP status codes which are considered transient
+	Statuses []int
+	// Categories are the job failure categories, such as RESOURCE, which are considered transient.
+	// a failed job with one of these categories is submitted again
+	Categories []string
+	// Resubmit retries a job submission which failed without a response, such as when the connection
+	// was reset, or with a transient status. the coordinator may have started the job anyway, so the
+	// query can run twice. only set it for queries which are safe to run more than once
+	Resubmit bool
+}
+
+// DefaultRetryPolicy is the retry policy used when none is provided
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	Backoff:     250 * time.Millisecond,
+	MaxBackoff:  5 * time.Second,
+	Statuses: []int{
+		http.StatusGone,
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+	Categories: []string{"RESOURCE", "CONNECTION", "IO_EXCEPTION"},
+}
+
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts <= 0 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// backoff returns the time to wait after the attempt failed
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return d
+}
+
+func (p RetryPolicy) retryableStatus(code int) bool {
+	for _, s := range p.Statuses {
+		if s == code {
+			return true
+		}
+	}
+	return false
+}
+
+// retryableError returns true if a request which failed without a response can be sent again.
+// the request can't have been sent when the connection couldn't be established, otherwise it's
+// only sent again if it's idempotent. errors which won't go away on their own, such as an unknown
+// host or an invalid certificate, are never retried
+func retryableError(err error, idempotent bool) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	var certErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var recordErr tls.RecordHeaderError
+	if errors.As(err, &certErr) || errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) || errors.As(err, &recordErr) {
+		return false
+	}
+	return idempotent || unsent(err)
+}
+
+// unsent returns true if a request which failed without a response can't have reached the
+// coordinator, because the connection couldn't be established
+func unsent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func (p RetryPolicy) retryableJob(err *JobError) bool {
+	for _, c := range p.Categories {
+		if c == err.Category {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusError is returned when the coordinator responds with an unexpected HTTP status code
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+	}
+	return e.Body
+}
+
+// JobError is returned when a job fails on the coordinator
+type JobError struct {
+	JobID string
+	// Category is the category of the failure, such as RESOURCE or SCHEMA_CHANGE. may be empty
+	Category string
+	Message  string
+}
+
+func (e *JobError) Error() string {
+	return e.Message
+}
+
+var categoryRe = regexp.MustCompile(`\b([A-Z_]+) ERROR\b`)
+
+func newJobError(jobid string, message string) *JobError {
+	err := &JobError{JobID: jobid, Message: message}
+	if m := categoryRe.FindStringSubmatch(message); m != nil {
+		err.Category = m[1]
+	}
+	return err
+}
+
+// SchemaChangeError is returned when a query keeps failing with a schema change
+// error after being resubmitted the maximum number of times
+type SchemaChangeError struct {
+	// Attempts is the number of times the query was submitted
+	Attempts int
+	// Err is the error of the last attempt
+	Err *JobError
+}
+
+func (e *SchemaChangeError) Error() string {
+	return fmt.Sprintf("query failed after %d attempts. %v", e.Attempts, e.Err)
+}
+
+func (e *SchemaChangeError) Unwrap() error {
+	return e.Err
+}
+
+// SchemaChangeFunc is called before a query which failed with a schema change
+// error is resubmitted. attempt is the number of the attempt which failed
+type SchemaChangeFunc func(ctx context.Context, jobid string, attempt int, err error)
+
+// sleep waits for the duration or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
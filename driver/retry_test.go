@@ -0,0 +1,234 @@
+package driver
+
+import (
+	"context"
+	"crypto/x509"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestConnector(t *testing.T, srv *httptest.Server) *Connector {
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+	c, err := NewConnector(Config{
+		Proto:    u.Scheme,
+		Hostname: u.Hostname(),
+		Port:     port,
+		Username: "user",
+		Password: "pass",
+		Retry: RetryPolicy{
+			MaxAttempts: 3,
+			Backoff:     time.Millisecond,
+			Statuses:    DefaultRetryPolicy.Statuses,
+			Categories:  DefaultRetryPolicy.Categories,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestRetryTransientStatus(t *testing.T) {
+	assert := assert.New(t)
+	var submits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiv2/login":
+			io.WriteString(w, `{"token":"abc"}`)
+		case "/api/v3/sql":
+			if atomic.AddInt32(&submits, 1) <= 2 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			io.WriteString(w, `{"id":"1"}`)
+		case "/api/v3/job/1":
+			io.WriteString(w, `{"jobState":"COMPLETED","rowCount":1}`)
+		case "/api/v3/job/1/results":
+			io.WriteString(w, `{"rowCount":1,"schema":[{"name":"a"}],"rows":[{"a":1}]}`)
+		}
+	}))
+	defer srv.Close()
+	c := newTestConnector(t, srv)
+	conn, err := c.Connect(context.Background())
+	assert.NoError(err)
+	_, err = conn.(driver.QueryerContext).QueryContext(context.Background(), "SELECT a FROM foo", nil)
+	var serr *StatusError
+	assert.True(errors.As(err, &serr))
+	assert.Equal(int32(1), atomic.LoadInt32(&submits))
+
+	c.cfg.Retry.Resubmit = true
+	conn, err = c.Connect(context.Background())
+	assert.NoError(err)
+	rows, err := conn.(driver.QueryerContext).QueryContext(context.Background(), "SELECT a FROM foo", nil)
+	assert.NoError(err)
+	assert.Equal([]string{"a"}, rows.Columns())
+	assert.Equal(int32(3), atomic.LoadInt32(&submits))
+}
+
+func TestSubmitIsNotRerun(t *testing.T) {
+	assert := assert.New(t)
+	var submits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiv2/login":
+			io.WriteString(w, `{"token":"abc"}`)
+		case "/api/v3/sql":
+			// the job is created, then the connection is dropped before the response
+			atomic.AddInt32(&submits, 1)
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+		}
+	}))
+	defer srv.Close()
+	db := sql.OpenDB(newTestConnector(t, srv))
+	defer db.Close()
+	_, err := db.QueryContext(context.Background(), "INSERT INTO t SELECT 1")
+	assert.Error(err)
+	assert.False(errors.Is(err, driver.ErrBadConn))
+	assert.Equal(int32(1), atomic.LoadInt32(&submits))
+}
+
+func TestRetryableError(t *testing.T) {
+	assert := assert.New(t)
+	dial := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	read := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	assert.True(retryableError(&url.Error{Op: "Post", Err: dial}, false))
+	assert.False(retryableError(&url.Error{Op: "Post", Err: read}, false))
+	assert.True(retryableError(&url.Error{Op: "Get", Err: read}, true))
+	assert.False(retryableError(&url.Error{Op: "Get", Err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", IsNotFound: true}}}, true))
+	assert.False(retryableError(&url.Error{Op: "Get", Err: x509.UnknownAuthorityError{}}, true))
+	assert.True(unsent(&url.Error{Op: "Post", Err: dial}))
+	assert.False(unsent(&url.Error{Op: "Post", Err: read}))
+}
+
+type testLogger struct {
+	nopLogger
+	warnings []string
+}
+
+func (l *testLogger) Warn(msg string, args ...interface{}) {
+	l.warnings = append(l.warnings, msg)
+}
+
+func TestRetryIsLogged(t *testing.T) {
+	assert := assert.New(t)
+	var logins int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&logins, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, `{"token":"abc"}`)
+	}))
+	defer srv.Close()
+	c := newTestConnector(t, srv)
+	logger := &testLogger{}
+	c.cfg.Logger = logger
+	_, err := c.Connect(context.Background())
+	assert.NoError(err)
+	assert.Equal([]string{"retrying request"}, logger.warnings)
+}
+
+func TestRetryTransientJobFailure(t *testing.T) {
+	assert := assert.New(t)
+	var submits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiv2/login":
+			io.WriteString(w, `{"token":"abc"}`)
+		case "/api/v3/sql":
+			io.WriteString(w, `{"id":"`+strconv.Itoa(int(atomic.AddInt32(&submits, 1)))+`"}`)
+		case "/api/v3/job/1":
+			io.WriteString(w, `{"jobState":"FAILED","errorMessage":"RESOURCE ERROR: queue full"}`)
+		case "/api/v3/job/2":
+			io.WriteString(w, `{"jobState":"FAILED","errorMessage":"VALIDATION ERROR: table not found"}`)
+		}
+	}))
+	defer srv.Close()
+	conn, err := newTestConnector(t, srv).Connect(context.Background())
+	assert.NoError(err)
+	_, err = conn.(driver.QueryerContext).QueryContext(context.Background(), "SELECT a FROM foo", nil)
+	var jerr *JobError
+	assert.True(errors.As(err, &jerr))
+	assert.Equal("2", jerr.JobID)
+	assert.Equal("VALIDATION", jerr.Category)
+	assert.Equal(int32(2), atomic.LoadInt32(&submits))
+}
+
+func TestUnauthorizedIsBadConn(t *testing.T) {
+	assert := assert.New(t)
+	var logins int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiv2/login":
+			atomic.AddInt32(&logins, 1)
+			io.WriteString(w, `{"token":"abc"}`)
+		case "/api/v3/sql":
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+	conn, err := newTestConnector(t, srv).Connect(context.Background())
+	assert.NoError(err)
+	_, err = conn.(driver.QueryerContext).QueryContext(context.Background(), "SELECT a FROM foo", nil)
+	assert.True(errors.Is(err, driver.ErrBadConn))
+	assert.Equal(int32(2), atomic.LoadInt32(&logins))
+}
+
+func TestRetryBackoff(t *testing.T) {
+	assert := assert.New(t)
+	p := RetryPolicy{Backoff: time.Second, MaxBackoff: 3 * time.Second}
+	assert.Equal(time.Second, p.backoff(1))
+	assert.Equal(2*time.Second, p.backoff(2))
+	assert.Equal(3*time.Second, p.backoff(3))
+	assert.Equal(3*time.Second, p.backoff(10))
+}
+
+func TestSchemaChangeIsBounded(t *testing.T) {
+	assert := assert.New(t)
+	var submits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiv2/login":
+			io.WriteString(w, `{"token":"abc"}`)
+		case "/api/v3/sql":
+			atomic.AddInt32(&submits, 1)
+			io.WriteString(w, `{"id":"1"}`)
+		case "/api/v3/job/1":
+			io.WriteString(w, `{"jobState":"FAILED","errorMessage":"SCHEMA_CHANGE ERROR: new schema found"}`)
+		}
+	}))
+	defer srv.Close()
+	c := newTestConnector(t, srv)
+	c.cfg.SchemaChangeAttempts = 4
+	c.cfg.SchemaChangeBackoff = time.Millisecond
+	var observed []int
+	c.cfg.OnSchemaChange = func(ctx context.Context, jobid string, attempt int, err error) {
+		observed = append(observed, attempt)
+	}
+	conn, err := c.Connect(context.Background())
+	assert.NoError(err)
+	_, err = conn.(driver.QueryerContext).QueryContext(context.Background(), "SELECT a FROM foo", nil)
+	var serr *SchemaChangeError
+	assert.True(errors.As(err, &serr))
+	assert.Equal(4, serr.Attempts)
+	assert.Equal("SCHEMA_CHANGE", serr.Err.Category)
+	assert.Equal([]int{1, 2, 3}, observed)
+	assert.Equal(int32(4), atomic.LoadInt32(&submits))
+}
@@ -0,0 +1,182 @@
+package driver
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// splitStatements splits a script into its statements on the semicolons which
+// aren't inside a string, a quoted identifier or a comment. empty statements are dropped
+func splitStatements(script string) []string {
+	var statements []string
+	var start int
+	add := func(end int) {
+		if s := strings.TrimSpace(script[start:end]); s != "" {
+			statements = append(statements, s)
+		}
+	}
+	for i := 0; i < len(script); i++ {
+		switch script[i] {
+		case '\'', '"':
+			// skip to the closing quote, a doubled quote is an escaped quote
+			quote := script[i]
+			for i++; i < len(script); i++ {
+				if script[i] == quote {
+					if i+1 < len(script) && script[i+1] == quote {
+						i++
+						continue
+					}
+					break
+				}
+			}
+		case '-':
+			if strings.HasPrefix(script[i:], "--") {
+				if end := strings.IndexByte(script[i:], '\n'); end >= 0 {
+					i += end
+				} else {
+					i = len(script)
+				}
+			}
+		case '/':
+			if strings.HasPrefix(script[i:], "/*") {
+				if end := strings.Index(script[i+2:], "*/"); end >= 0 {
+					i += end + 3
+				} else {
+					i = len(script)
+				}
+			}
+		case ';':
+			add(i)
+			start = i + 1
+		}
+	}
+	if start < len(script) {
+		add(len(script))
+	}
+	return statements
+}
+
+// StatementError is the error of a statement of a script
+type StatementError struct {
+	// Index is the position of the statement in the script, starting at 0
+	Index     int
+	Statement string
+	Err       error
+}
+
+func (e *StatementError) Error() string {
+	return fmt.Sprintf("statement %d failed. %v", e.Index+1, e.Err)
+}
+
+func (e *StatementError) Unwrap() error {
+	return e.Err
+}
+
+// ScriptError is returned after the last result set of a script run with
+// ContinueOnError when some of its statements failed
+type ScriptError struct {
+	Errors []*StatementError
+}
+
+func (e *ScriptError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d statements failed. %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// scriptRows runs the statements of a script one after the other as separate jobs,
+// each statement is a result set
+type scriptRows struct {
+	ctx        context.Context
+	conn       *connection
+	query      query
+	statements []string
+	index      int
+	current    driver.Rows
+	errs       []*StatementError
+}
+
+var _ driver.RowsNextResultSet = (*scriptRows)(nil)
+
+func newScriptRows(ctx context.Context, c *connection, q query, args []driver.NamedValue) (driver.Rows, error) {
+	r := &scriptRows{
+		ctx:        ctx,
+		conn:       c,
+		query:      q,
+		statements: splitStatements(q.bind(args).Query),
+	}
+	if err := r.NextResultSet(); err != nil {
+		if err == io.EOF {
+			// the script only has empty statements
+			return nil, fmt.Errorf("no statements to run")
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// Columns returns the names of the columns of the current result set.
+func (r *scriptRows) Columns() []string {
+	return r.current.Columns()
+}
+
+// Close closes the rows iterator.
+func (r *scriptRows) Close() error {
+	if r.current != nil {
+		return r.current.Close()
+	}
+	return nil
+}
+
+// Next is called to populate the next row of data of the current result set.
+func (r *scriptRows) Next(dest []driver.Value) error {
+	return r.current.Next(dest)
+}
+
+// HasNextResultSet is called at the end of the current result set and
+// reports whether there is another result set after the current one.
+func (r *scriptRows) HasNextResultSet() bool {
+	return r.index < len(r.statements) || len(r.errs) > 0
+}
+
+// NextResultSet advances the driver to the next result set even
+// if there are remaining rows in the current result set.
+//
+// NextResultSet should return io.EOF when there are no more result sets.
+func (r *scriptRows) NextResultSet() error {
+	if r.current != nil {
+		r.current.Close()
+		r.current = nil
+	}
+	for r.index < len(r.statements) {
+		index := r.index
+		r.index++
+		// each statement runs with the context path, references and engine of the script
+		q := r.query
+		q.Query, q.args = r.statements[index], nil
+		rows, err := q.execute(r.ctx, r.conn, nil)
+		if err != nil {
+			serr := &StatementError{Index: index, Statement: r.statements[index], Err: err}
+			if !r.conn.continueOnError {
+				r.index = len(r.statements)
+				return serr
+			}
+			r.conn.log.Warn("statement failed, continuing with the next one", "statement", index+1, "err", err)
+			r.errs = append(r.errs, serr)
+			continue
+		}
+		r.current = rows
+		return nil
+	}
+	if len(r.errs) > 0 {
+		err := &ScriptError{Errors: r.errs}
+		r.errs = nil
+		return err
+	}
+	return io.EOF
+}
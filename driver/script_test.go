@@ -0,0 +1,106 @@
+package driver
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal([]string{"SELECT 1"}, splitStatements("SELECT 1;"))
+	assert.Equal([]string{"SELECT 1", "SELECT 2"}, splitStatements("SELECT 1; SELECT 2"))
+	assert.Equal([]string{"SELECT ';' FROM \"a;b\"", "SELECT 'it''s;'"}, splitStatements("SELECT ';' FROM \"a;b\";\n SELECT 'it''s;' ;;"))
+	assert.Equal([]string{"SELECT 1 -- one; two", "/* three; */ SELECT 3"}, splitStatements("SELECT 1 -- one; two\n; /* three; */ SELECT 3"))
+	assert.Empty(splitStatements(" ; "))
+}
+
+func newScriptServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/apiv2/login":
+			io.WriteString(w, `{"token":"abc"}`)
+		case r.URL.Path == "/api/v3/sql":
+			var q query
+			json.NewDecoder(r.Body).Decode(&q)
+			// the job id is the number selected by the statement
+			io.WriteString(w, `{"id":"`+strings.TrimSpace(strings.TrimPrefix(q.Query, "SELECT"))+`"}`)
+		case r.URL.Path == "/api/v3/job/0":
+			io.WriteString(w, `{"jobState":"FAILED","errorMessage":"VALIDATION ERROR: division by zero"}`)
+		case strings.HasSuffix(r.URL.Path, "/results"):
+			id := strings.Split(r.URL.Path, "/")[4]
+			io.WriteString(w, `{"rowCount":1,"schema":[{"name":"n"}],"rows":[{"n":"`+id+`"}]}`)
+		default:
+			io.WriteString(w, `{"jobState":"COMPLETED","rowCount":1}`)
+		}
+	}))
+}
+
+func readResultSets(rows *sql.Rows) []string {
+	var vals []string
+	for {
+		for rows.Next() {
+			var n string
+			rows.Scan(&n)
+			vals = append(vals, n)
+		}
+		if !rows.NextResultSet() {
+			return vals
+		}
+	}
+}
+
+func TestScriptResultSets(t *testing.T) {
+	assert := assert.New(t)
+	srv := newScriptServer()
+	defer srv.Close()
+	db := sql.OpenDB(newTestConnector(t, srv))
+	defer db.Close()
+	rows, err := db.Query("SELECT 1; SELECT 2; SELECT 3")
+	assert.NoError(err)
+	defer rows.Close()
+	assert.Equal([]string{"1", "2", "3"}, readResultSets(rows))
+	assert.NoError(rows.Err())
+}
+
+func TestScriptStopOnError(t *testing.T) {
+	assert := assert.New(t)
+	srv := newScriptServer()
+	defer srv.Close()
+	db := sql.OpenDB(newTestConnector(t, srv))
+	defer db.Close()
+	rows, err := db.Query("SELECT 1; SELECT 0; SELECT 3")
+	assert.NoError(err)
+	defer rows.Close()
+	assert.Equal([]string{"1"}, readResultSets(rows))
+	var serr *StatementError
+	assert.True(errors.As(rows.Err(), &serr))
+	assert.Equal(1, serr.Index)
+	assert.Equal("SELECT 0", serr.Statement)
+}
+
+func TestScriptContinueOnError(t *testing.T) {
+	assert := assert.New(t)
+	srv := newScriptServer()
+	defer srv.Close()
+	c := newTestConnector(t, srv)
+	c.cfg.ContinueOnError = true
+	db := sql.OpenDB(c)
+	defer db.Close()
+	rows, err := db.Query("SELECT 0; SELECT 2; SELECT 0; SELECT 4")
+	assert.NoError(err)
+	defer rows.Close()
+	assert.Equal([]string{"2", "4"}, readResultSets(rows))
+	var serr *ScriptError
+	assert.True(errors.As(rows.Err(), &serr))
+	assert.Len(serr.Errors, 2)
+	assert.Equal(0, serr.Errors[0].Index)
+	assert.Equal(2, serr.Errors[1].Index)
+}
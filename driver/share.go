@@ -0,0 +1,218 @@
+package driver
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"time"
+)
+
+// queryGroup deduplicates identical queries running at the same time on the connections
+// of a connector. the first query submits the job and the others wait for it, then each
+// of them reads the results with its own rows over pages which are only fetched once
+type queryGroup struct {
+	mu    sync.Mutex
+	calls map[string]*sharedCall
+}
+
+type sharedCall struct {
+	done  chan struct{}
+	pages *sharedPages
+	err   error
+	// waiters is the number of identical queries waiting for the call, guarded by the mutex of the group
+	waiters int
+}
+
+// sharedPages are the pages of a job read by several queries. a page is only kept
+// until each of the readers has read it or closed its rows
+type sharedPages struct {
+	mu      sync.Mutex
+	jobid   string
+	total   int
+	columns columns
+	schema  []schema
+	readers int
+	pages   map[int]*sharedPage
+}
+
+// sharedPage is a page of a job, ready once the reader fetching it is done
+type sharedPage struct {
+	ready chan struct{}
+	rows  []map[string]interface{}
+	err   error
+	// left is the number of readers which haven't read the page yet
+	left int
+}
+
+func newQueryGroup() *queryGroup {
+	return &queryGroup{calls: make(map[string]*sharedCall)}
+}
+
+// newSharedPages returns the pages of the result of a query, to be read by the followers too.
+// the query has already read the first page
+func newSharedPages(res *result, followers int) *sharedPages {
+	first := &sharedPage{ready: make(chan struct{}), rows: res.rows.rows, left: followers}
+	close(first.ready)
+	return &sharedPages{
+		jobid:   res.jobid,
+		total:   res.total,
+		columns: res.columns,
+		schema:  res.schema,
+		readers: followers + 1,
+		pages:   map[int]*sharedPage{0: first},
+	}
+}
+
+// fetch sets the rows of the result to the page at its offset, fetching it with
+// the connection of the result if no other query has fetched it yet
+func (s *sharedPages) fetch(res *result) error {
+	for {
+		s.mu.Lock()
+		page, ok := s.pages[res.offset]
+		if !ok {
+			// fetch it without holding the lock, the other readers wait for it to be ready
+			page = &sharedPage{ready: make(chan struct{}), left: s.readers}
+			s.pages[res.offset] = page
+			s.mu.Unlock()
+			tmp := &result{ctx: res.ctx, conn: res.conn, jobid: s.jobid, info: res.info, columns: s.columns, offset: res.offset}
+			err := fetchNextPage(res.ctx, res.conn, s.jobid, res.offset, s.total, tmp)
+			s.mu.Lock()
+			if err != nil {
+				delete(s.pages, res.offset)
+				page.err = err
+			} else {
+				page.rows = tmp.rows.rows
+			}
+			close(page.ready)
+			s.mu.Unlock()
+			if err != nil {
+				return err
+			}
+		} else {
+			s.mu.Unlock()
+		}
+		select {
+		case <-page.ready:
+		case <-res.ctx.Done():
+			return res.ctx.Err()
+		}
+		if page.err != nil {
+			// the reader fetching it failed, fetch it again
+			continue
+		}
+		s.mu.Lock()
+		s.read(res.offset, page)
+		s.mu.Unlock()
+		res.offset += len(page.rows)
+		res.rows = &rows{parent: res, rows: page.rows}
+		res.record(page.rows)
+		return nil
+	}
+}
+
+// read drops the page at the offset once every reader has read it. s.mu must be held
+func (s *sharedPages) read(offset int, page *sharedPage) {
+	page.left--
+	if page.left <= 0 {
+		delete(s.pages, offset)
+	}
+}
+
+// leave removes a reader which won't read the pages from its offset on, such as when its
+// rows are closed
+func (s *sharedPages) leave(offset int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readers--
+	for o, page := range s.pages {
+		if o >= offset {
+			s.read(o, page)
+		}
+	}
+}
+
+// share runs the query, or waits for an identical query which is already running
+// and shares its job. buf is the request with the final SQL
+func (q query) share(ctx context.Context, c *connection, buf []byte) (driver.Rows, error) {
+	if c.queries == nil {
+		return q.send(ctx, c, buf)
+	}
+	g := c.queries
+	key := c.cacheKey(buf, q)
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		call.waiters++
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			g.mu.Lock()
+			if g.calls[key] == call {
+				call.waiters--
+			} else if call.pages != nil {
+				// the pages were already shared with us
+				call.pages.leave(0)
+			}
+			g.mu.Unlock()
+			return nil, ctx.Err()
+		}
+		if call.err != nil {
+			if (errors.Is(call.err, context.Canceled) || errors.Is(call.err, context.DeadlineExceeded)) && ctx.Err() == nil {
+				// the query we waited for was cancelled by its caller, not by us
+				return q.send(ctx, c, buf)
+			}
+			return nil, call.err
+		}
+		c.log.Debug("sharing the job of an identical query", "job", call.pages.jobid)
+		return q.shared(ctx, c, call.pages), nil
+	}
+	call := &sharedCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	dr, err := q.send(ctx, c, buf)
+	g.mu.Lock()
+	delete(g.calls, key)
+	if err == nil && call.waiters > 0 {
+		// only share the pages with the queries which are waiting, no others can join now
+		res := dr.(*rows).parent
+		res.stopPrefetch()
+		res.prefetch = false
+		call.pages = newSharedPages(res, call.waiters)
+		res.shared = call.pages
+	}
+	call.err = err
+	g.mu.Unlock()
+	close(call.done)
+	return dr, err
+}
+
+// shared returns new rows over the pages of the job of another query
+func (q query) shared(ctx context.Context, c *connection, pages *sharedPages) *rows {
+	info := &QueryInfo{
+		SQL:      q.Query,
+		Args:     q.args,
+		Context:  q.Context,
+		Endpoint: c.endpoint(),
+		Started:  time.Now(),
+	}
+	ctx = c.observer.QuerySubmitted(ctx, info)
+	pages.mu.Lock()
+	first := pages.pages[0].rows
+	pages.read(0, pages.pages[0])
+	pages.mu.Unlock()
+	res := &result{
+		ctx:     ctx,
+		jobid:   pages.jobid,
+		conn:    c,
+		info:    info,
+		columns: pages.columns,
+		schema:  pages.schema,
+		offset:  len(first),
+		total:   pages.total,
+		shared:  pages,
+	}
+	res.rows = &rows{parent: res, rows: first}
+	return res.rows
+}
@@ -0,0 +1,122 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeduplicate(t *testing.T) {
+	assert := assert.New(t)
+	var submits, pages int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiv2/login":
+			io.WriteString(w, `{"token":"abc"}`)
+		case "/api/v3/sql":
+			atomic.AddInt32(&submits, 1)
+			io.WriteString(w, `{"id":"1"}`)
+		case "/api/v3/job/1":
+			// give the other queries time to arrive while the job runs
+			time.Sleep(200 * time.Millisecond)
+			io.WriteString(w, `{"jobState":"COMPLETED","rowCount":3}`)
+		case "/api/v3/job/1/results":
+			if r.URL.Query().Get("offset") == "2" {
+				atomic.AddInt32(&pages, 1)
+				io.WriteString(w, `{"rowCount":3,"schema":[{"name":"a"}],"rows":[{"a":3}]}`)
+				return
+			}
+			io.WriteString(w, `{"rowCount":3,"schema":[{"name":"a"}],"rows":[{"a":1},{"a":2}]}`)
+		}
+	}))
+	defer srv.Close()
+	c := newTestConnector(t, srv)
+	c.cfg.PageSize = 2
+	c.queries = newQueryGroup()
+	db := sql.OpenDB(c)
+	defer db.Close()
+
+	var wg sync.WaitGroup
+	results := make([][]int, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rows, err := db.Query("SELECT a FROM foo")
+			if !assert.NoError(err) {
+				return
+			}
+			defer rows.Close()
+			for rows.Next() {
+				var a int
+				assert.NoError(rows.Scan(&a))
+				results[i] = append(results[i], a)
+			}
+			assert.NoError(rows.Err())
+		}(i)
+	}
+	wg.Wait()
+	for _, vals := range results {
+		assert.Equal([]int{1, 2, 3}, vals)
+	}
+	assert.Equal(int32(1), atomic.LoadInt32(&submits))
+	assert.Equal(int32(1), atomic.LoadInt32(&pages))
+}
+
+func TestSharedPagesAreDropped(t *testing.T) {
+	assert := assert.New(t)
+	leader := &result{ctx: context.Background(), jobid: "1", total: 3, rows: &rows{rows: []map[string]interface{}{{"a": 1}, {"a": 2}}}}
+	pages := newSharedPages(leader, 2)
+	pages.pages[2] = &sharedPage{ready: make(chan struct{}), rows: []map[string]interface{}{{"a": 3}}, left: 3}
+	close(pages.pages[2].ready)
+	leader.shared = pages
+	leader.offset = 2
+
+	follower := query{}.shared(context.Background(), &connection{observer: NopObserver{}}, pages)
+	assert.Len(follower.rows, 2)
+	assert.NoError(pages.fetch(leader))
+	assert.Equal(3, leader.offset)
+	// the first page is kept for the other follower, which leaves without reading it
+	assert.Len(pages.pages, 2)
+	pages.leave(0)
+	assert.Len(pages.pages, 1)
+	assert.NoError(pages.fetch(follower.parent))
+	assert.Empty(pages.pages)
+	leader.complete(nil)
+	follower.parent.complete(nil)
+	assert.Equal(0, pages.readers)
+}
+
+func TestDeduplicateAlone(t *testing.T) {
+	assert := assert.New(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiv2/login":
+			io.WriteString(w, `{"token":"abc"}`)
+		case "/api/v3/sql":
+			io.WriteString(w, `{"id":"1"}`)
+		case "/api/v3/job/1":
+			io.WriteString(w, `{"jobState":"COMPLETED","rowCount":1}`)
+		case "/api/v3/job/1/results":
+			io.WriteString(w, `{"rowCount":1,"schema":[{"name":"a"}],"rows":[{"a":1}]}`)
+		}
+	}))
+	defer srv.Close()
+	c := newTestConnector(t, srv)
+	c.queries = newQueryGroup()
+	conn, err := c.Connect(context.Background())
+	assert.NoError(err)
+	dr, err := conn.(driver.QueryerContext).QueryContext(context.Background(), "SELECT a FROM foo", nil)
+	assert.NoError(err)
+	// nobody joined, so the pages aren't kept
+	assert.Nil(dr.(*rows).parent.shared)
+}
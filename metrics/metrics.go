@@ -0,0 +1,224 @@
+// Package metrics provides a prometheus.Collector which records the lifecycle
+// of the queries run by the dremio driver.
+//
+//	collector := metrics.NewCollector()
+//	prometheus.MustRegister(collector)
+//	connector, err := driver.NewConnector(driver.Config{..., Observer: collector})
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pinpt/go-dremio/driver"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is a prometheus.Collector and a driver.Observer. Every metric is labelled
+// by the endpoint (host and port) of the coordinator
+type Collector struct {
+	driver.NopObserver
+
+	submission *prometheus.HistogramVec
+	queue      *prometheus.HistogramVec
+	execution  *prometheus.HistogramVec
+	pageFetch  *prometheus.HistogramVec
+	rows       *prometheus.HistogramVec
+	failures   *prometheus.CounterVec
+	retries    *prometheus.CounterVec
+	relogins   *prometheus.CounterVec
+	slotWait   *prometheus.HistogramVec
+
+	mu   sync.Mutex
+	jobs map[string]*jobTimes
+}
+
+type jobTimes struct {
+	queue     time.Duration
+	execution time.Duration
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+var _ driver.Observer = (*Collector)(nil)
+var _ driver.RetryObserver = (*Collector)(nil)
+var _ driver.LimitObserver = (*Collector)(nil)
+
+const namespace = "dremio"
+
+// NewCollector returns a new Collector
+func NewCollector() *Collector {
+	return &Collector{
+		submission: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "job_submission_seconds",
+			Help:      "Time taken to submit a job",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"endpoint"}),
+		queue: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "job_queue_seconds",
+			Help:      "Time a job spent before running, such as queued, planning or starting",
+			Buckets:   prometheus.ExponentialBuckets(0.5, 2, 10),
+		}, []string{"endpoint"}),
+		execution: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "job_execution_seconds",
+			Help:      "Time a job spent running",
+			Buckets:   prometheus.ExponentialBuckets(0.5, 2, 10),
+		}, []string{"endpoint"}),
+		pageFetch: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "page_fetch_seconds",
+			Help:      "Time taken to fetch a page of results",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"endpoint"}),
+		rows: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "query_rows",
+			Help:      "Number of rows read from a query",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 10),
+		}, []string{"endpoint"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "query_failures_total",
+			Help:      "Number of failed queries by error category",
+		}, []string{"endpoint", "category"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "retries_total",
+			Help:      "Number of retries by kind",
+		}, []string{"endpoint", "kind"}),
+		relogins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "relogins_total",
+			Help:      "Number of times a connection logged in again after its token was rejected",
+		}, []string{"endpoint"}),
+		slotWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "job_slot_wait_seconds",
+			Help:      "Time a query waited for a job slot when the number of concurrent jobs is limited",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 4, 10),
+		}, []string{"endpoint"}),
+		jobs: make(map[string]*jobTimes),
+	}
+}
+
+func (c *Collector) metrics() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.submission,
+		c.queue,
+		c.execution,
+		c.pageFetch,
+		c.rows,
+		c.failures,
+		c.retries,
+		c.relogins,
+		c.slotWait,
+	}
+}
+
+// Describe sends the descriptors of all the metrics of the collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.metrics() {
+		m.Describe(ch)
+	}
+}
+
+// Collect sends all the metrics of the collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range c.metrics() {
+		m.Collect(ch)
+	}
+}
+
+// JobCreated records the submission latency
+func (c *Collector) JobCreated(ctx context.Context, q *driver.QueryInfo, e driver.JobCreatedEvent) {
+	c.submission.WithLabelValues(q.Endpoint).Observe(e.Duration.Seconds())
+}
+
+// JobStateChanged records the queue and execution time once the job is done
+func (c *Collector) JobStateChanged(ctx context.Context, q *driver.QueryInfo, e driver.JobStateEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	times := c.jobs[e.JobID]
+	if times == nil {
+		times = &jobTimes{}
+		c.jobs[e.JobID] = times
+	}
+	if e.Previous == "RUNNING" {
+		times.execution += e.Duration
+	} else {
+		times.queue += e.Duration
+	}
+	switch e.State {
+	case "COMPLETED", "FAILED", "CANCELED":
+		c.queue.WithLabelValues(q.Endpoint).Observe(times.queue.Seconds())
+		c.execution.WithLabelValues(q.Endpoint).Observe(times.execution.Seconds())
+		delete(c.jobs, e.JobID)
+	}
+}
+
+// PageFetched records the page fetch latency
+func (c *Collector) PageFetched(ctx context.Context, q *driver.QueryInfo, e driver.PageEvent) {
+	c.pageFetch.WithLabelValues(q.Endpoint).Observe(e.Duration.Seconds())
+}
+
+// QueryCompleted records the number of rows read
+func (c *Collector) QueryCompleted(ctx context.Context, q *driver.QueryInfo, e driver.QueryCompletedEvent) {
+	c.rows.WithLabelValues(q.Endpoint).Observe(float64(e.Rows))
+	c.forget(e.JobID)
+}
+
+// QueryFailed counts the failure by its category
+func (c *Collector) QueryFailed(ctx context.Context, q *driver.QueryInfo, e driver.QueryFailedEvent) {
+	c.failures.WithLabelValues(q.Endpoint, Category(e.Err)).Inc()
+	c.forget(e.JobID)
+}
+
+// forget drops the times of a job whose query is done, such as when waiting for the job
+// was cancelled before it reached a final state
+func (c *Collector) forget(jobID string) {
+	if jobID == "" {
+		return
+	}
+	c.mu.Lock()
+	delete(c.jobs, jobID)
+	c.mu.Unlock()
+}
+
+// Retried counts the retry by its kind
+func (c *Collector) Retried(ctx context.Context, e driver.RetryEvent) {
+	c.retries.WithLabelValues(e.Endpoint, e.Kind).Inc()
+}
+
+// Relogin counts the re-login
+func (c *Collector) Relogin(ctx context.Context, e driver.ReloginEvent) {
+	c.relogins.WithLabelValues(e.Endpoint).Inc()
+}
+
+// JobSlotAcquired records the time waited for a job slot
+func (c *Collector) JobSlotAcquired(ctx context.Context, q *driver.QueryInfo, e driver.LimitEvent) {
+	c.slotWait.WithLabelValues(e.Endpoint).Observe(e.Wait.Seconds())
+}
+
+// Category returns the category used to label a failure. for a job failure this is
+// the Dremio error category, such as RESOURCE or VALIDATION
+func Category(err error) string {
+	var jerr *driver.JobError
+	var serr *driver.StatusError
+	switch {
+	case errors.As(err, &jerr):
+		if jerr.Category == "" {
+			return "UNKNOWN"
+		}
+		return jerr.Category
+	case errors.As(err, &serr):
+		return "HTTP_" + strconv.Itoa(serr.StatusCode)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "CONTEXT"
+	}
+	return "OTHER"
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pinpt/go-dremio/driver"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategory(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("RESOURCE", Category(&driver.JobError{Category: "RESOURCE"}))
+	assert.Equal("SCHEMA_CHANGE", Category(&driver.SchemaChangeError{Err: &driver.JobError{Category: "SCHEMA_CHANGE"}}))
+	assert.Equal("HTTP_502", Category(&driver.StatusError{StatusCode: 502}))
+	assert.Equal("CONTEXT", Category(context.Canceled))
+	assert.Equal("OTHER", Category(errors.New("boom")))
+}
+
+func TestCollector(t *testing.T) {
+	assert := assert.New(t)
+	c := NewCollector()
+	ctx := context.Background()
+	q := &driver.QueryInfo{SQL: "SELECT 1", Endpoint: "localhost:9047"}
+	c.JobCreated(ctx, q, driver.JobCreatedEvent{JobID: "1", Attempt: 1, Duration: time.Millisecond})
+	c.JobStateChanged(ctx, q, driver.JobStateEvent{JobID: "1", State: "RUNNING", Duration: time.Second})
+	c.JobStateChanged(ctx, q, driver.JobStateEvent{JobID: "1", State: "COMPLETED", Previous: "RUNNING", Duration: time.Second})
+	c.QueryFailed(ctx, q, driver.QueryFailedEvent{Err: &driver.JobError{Category: "RESOURCE"}})
+	c.Retried(ctx, driver.RetryEvent{Endpoint: "localhost:9047", Kind: driver.RetryRequest})
+	c.Relogin(ctx, driver.ReloginEvent{Endpoint: "localhost:9047"})
+	c.JobSlotAcquired(ctx, q, driver.LimitEvent{Endpoint: "localhost:9047", Wait: time.Second})
+	assert.Equal(1.0, testutil.ToFloat64(c.failures.WithLabelValues("localhost:9047", "RESOURCE")))
+	assert.Equal(1.0, testutil.ToFloat64(c.retries.WithLabelValues("localhost:9047", driver.RetryRequest)))
+	assert.Equal(1.0, testutil.ToFloat64(c.relogins.WithLabelValues("localhost:9047")))
+	assert.Equal(1, testutil.CollectAndCount(c.execution))
+	assert.Equal(1, testutil.CollectAndCount(c.slotWait))
+	assert.Empty(c.jobs)
+}
+
+func TestCollectorForgetsCancelledJobs(t *testing.T) {
+	assert := assert.New(t)
+	c := NewCollector()
+	ctx := context.Background()
+	q := &driver.QueryInfo{SQL: "SELECT 1", Endpoint: "localhost:9047"}
+	c.JobStateChanged(ctx, q, driver.JobStateEvent{JobID: "1", State: "RUNNING", Duration: time.Second})
+	c.QueryFailed(ctx, q, driver.QueryFailedEvent{JobID: "1", Err: context.Canceled})
+	assert.Empty(c.jobs)
+}